@@ -1,3 +1,5 @@
+// +build cgo,!purego
+
 #include <stdexcept>
 #include <cstring>
 
@@ -7,8 +9,10 @@
 extern "C" {
 #include "_cgo_export.h"
 }
+#include "helpers.h"
 
 using namespace unity::scopes;
+using namespace gounityscopes::internal;
 
 void activation_response_init_status(_ActivationResponse *response, int status) {
     *reinterpret_cast<ActivationResponse*>(response) =
@@ -25,6 +29,6 @@ void activation_response_set_scope_data(_ActivationResponse *response, char *jso
         Variant v = Variant::deserialize_json(std::string(json_data, json_data_length));
         reinterpret_cast<ActivationResponse*>(response)->set_scope_data(v);
     } catch (const std::exception &e) {
-        *error = strdup(e.what());
+        *error = exception_string(e);
     }
 }
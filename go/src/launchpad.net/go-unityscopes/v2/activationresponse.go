@@ -1,13 +1,5 @@
 package scopes
 
-// #include <stdlib.h>
-// #include "shim.h"
-import "C"
-import (
-	"encoding/json"
-	"unsafe"
-)
-
 type ActivationStatus int
 
 const (
@@ -51,26 +43,6 @@ func NewActivationResponseForQuery(query *CannedQuery) *ActivationResponse {
 	}
 }
 
-func (r *ActivationResponse) update(responsePtr *C._ActivationResponse) error {
-	if r.Status == ActivationPerformQuery {
-		C.activation_response_init_query(responsePtr, r.Query.q)
-	} else {
-		C.activation_response_init_status(responsePtr, C.int(r.Status))
-	}
-	if r.ScopeData != nil {
-		data, err := json.Marshal(r.ScopeData)
-		if err != nil {
-			return err
-		}
-		var errorString *C.char
-		C.activation_response_set_scope_data(responsePtr, (*C.char)(unsafe.Pointer(&data[0])), C.int(len(data)), &errorString)
-		if err = checkError(errorString); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
 // SetScopeData stores data that will be passed through to the preview
 // for ActivationShowPreview type responses.
 func (r *ActivationResponse) SetScopeData(v interface{}) {
@@ -0,0 +1,298 @@
+package scopes
+
+import (
+	"container/list"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// cacheKey identifies the searches that share cached results.
+type cacheKey struct {
+	QueryString  string      `json:"query_string"`
+	DepartmentID string      `json:"department_id"`
+	FilterState  FilterState `json:"filter_state"`
+	Locale       string      `json:"locale"`
+}
+
+type cachedResult struct {
+	category            string
+	attrs               map[string]json.RawMessage
+	interceptActivation bool
+}
+
+type cachedFilters struct {
+	filters []Filter
+	state   FilterState
+}
+
+// cachedReply holds everything pushed in reply to a search.
+type cachedReply struct {
+	key         string
+	generation  uint64
+	expires     time.Time
+	categories  []recordedCategory
+	departments *Department
+	filters     *cachedFilters
+	results     []cachedResult
+}
+
+// ResultCache memoizes the replies to searches, so that repeated
+// queries can be answered without calling the scope's Search method.
+//
+// Replies are cached by query string, department, filter state and
+// locale, and expire after a fixed time to live.  Once the cache holds
+// its maximum number of replies, the least recently used reply is
+// evicted.  Searches that fail or are cancelled are not cached.
+type ResultCache struct {
+	// Cacheable decides whether the reply to a search may be served
+	// from and stored in the cache.  If nil, all searches are
+	// cacheable.
+	Cacheable func(query *CannedQuery, metadata *SearchMetadata) bool
+
+	lock    sync.Mutex
+	ttl     time.Duration
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+	// generation is bumped by Purge, so that searches running at
+	// the time don't store their now invalid replies.
+	generation uint64
+}
+
+// NewResultCache creates a ResultCache holding up to size replies for
+// at most ttl each.
+func NewResultCache(ttl time.Duration, size int) *ResultCache {
+	return &ResultCache{
+		ttl:     ttl,
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Len returns the number of replies in the cache, including any that
+// have expired but not yet been evicted.
+func (cache *ResultCache) Len() int {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	return cache.order.Len()
+}
+
+// Purge removes all cached replies.  Scopes should call it when a
+// change means earlier replies are no longer valid.  Searches that
+// were already running when Purge was called are not cached either.
+func (cache *ResultCache) Purge() {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	cache.order.Init()
+	cache.entries = make(map[string]*list.Element)
+	cache.generation++
+}
+
+func (cache *ResultCache) currentGeneration() uint64 {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	return cache.generation
+}
+
+func (cache *ResultCache) get(key string) *cachedReply {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	elem, ok := cache.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := elem.Value.(*cachedReply)
+	if time.Now().After(entry.expires) {
+		cache.order.Remove(elem)
+		delete(cache.entries, key)
+		return nil
+	}
+	cache.order.MoveToFront(elem)
+	return entry
+}
+
+func (cache *ResultCache) put(entry *cachedReply) {
+	if cache.size <= 0 {
+		return
+	}
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	if entry.generation != cache.generation {
+		return
+	}
+	entry.expires = time.Now().Add(cache.ttl)
+	if elem, ok := cache.entries[entry.key]; ok {
+		elem.Value = entry
+		cache.order.MoveToFront(elem)
+		return
+	}
+	cache.entries[entry.key] = cache.order.PushFront(entry)
+	for cache.order.Len() > cache.size {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*cachedReply).key)
+	}
+}
+
+func resultCacheKey(query *CannedQuery, metadata *SearchMetadata) (string, error) {
+	data, err := json.Marshal(cacheKey{
+		QueryString:  query.QueryString(),
+		DepartmentID: query.DepartmentID(),
+		FilterState:  query.FilterState(),
+		Locale:       metadata.Locale(),
+	})
+	return string(data), err
+}
+
+// replay pushes a cached reply to reply.
+func (entry *cachedReply) replay(reply SearchReplier) error {
+	categories := make(map[string]*Category, len(entry.categories))
+	for _, cat := range entry.categories {
+		categories[cat.Id] = reply.RegisterCategory(cat.Id, cat.Title, cat.Icon, cat.Template)
+	}
+	if entry.departments != nil {
+		reply.RegisterDepartments(entry.departments)
+	}
+	if entry.filters != nil {
+		if err := reply.PushFilters(entry.filters.filters, entry.filters.state); err != nil {
+			return err
+		}
+	}
+	results := make([]ResultSetter, len(entry.results))
+	for i, res := range entry.results {
+		result := reply.NewResult(categories[res.category])
+		for name, value := range res.attrs {
+			if err := result.Set(name, value); err != nil {
+				return err
+			}
+		}
+		if res.interceptActivation {
+			result.SetInterceptActivation()
+		}
+		results[i] = result
+	}
+	return PushResults(reply, results...)
+}
+
+// Middleware returns middleware that answers searches from the cache
+// when possible, and caches the replies of the scope otherwise.
+func (cache *ResultCache) Middleware() Middleware {
+	return Middleware{
+		Search: func(next SearchHandler) SearchHandler {
+			return func(query *CannedQuery, metadata *SearchMetadata, reply SearchReplier, cancelled <-chan bool) error {
+				if cache.Cacheable != nil && !cache.Cacheable(query, metadata) {
+					return next(query, metadata, reply, cancelled)
+				}
+				key, err := resultCacheKey(query, metadata)
+				if err != nil {
+					return next(query, metadata, reply, cancelled)
+				}
+				if entry := cache.get(key); entry != nil {
+					return entry.replay(reply)
+				}
+
+				caching := &cachingSearchReply{
+					SearchReplier: reply,
+					entry:         &cachedReply{key: key, generation: cache.currentGeneration()},
+				}
+				if err := next(query, metadata, caching, cancelled); err != nil {
+					return err
+				}
+				select {
+				case <-cancelled:
+				default:
+					if !caching.uncacheable {
+						cache.put(caching.entry)
+					}
+				}
+				return nil
+			}
+		},
+	}
+}
+
+// cachingSearchReply captures everything pushed to a reply so it can
+// be cached.
+type cachingSearchReply struct {
+	SearchReplier
+	lock        sync.Mutex
+	entry       *cachedReply
+	uncacheable bool
+}
+
+func (reply *cachingSearchReply) RegisterCategory(id, title, icon, template string) *Category {
+	reply.lock.Lock()
+	reply.entry.categories = append(reply.entry.categories, recordedCategory{id, title, icon, template})
+	reply.lock.Unlock()
+	return reply.SearchReplier.RegisterCategory(id, title, icon, template)
+}
+
+func (reply *cachingSearchReply) RegisterDepartments(parent *Department) {
+	reply.lock.Lock()
+	reply.entry.departments = parent
+	reply.lock.Unlock()
+	reply.SearchReplier.RegisterDepartments(parent)
+}
+
+func (reply *cachingSearchReply) NewResult(category *Category) ResultSetter {
+	return newRecordingResult(reply.SearchReplier.NewResult(category), category)
+}
+
+// unwrap returns the result to pass on to the wrapped reply, and
+// whether its attributes could be captured.
+func (reply *cachingSearchReply) unwrap(result ResultSetter) (ResultSetter, *cachedResult) {
+	switch res := result.(type) {
+	case *recordingResult:
+		return res.ResultSetter, &cachedResult{
+			category:            res.category,
+			attrs:               res.attrs,
+			interceptActivation: res.interceptActivation,
+		}
+	case *ResultBuilder:
+		return res, &cachedResult{
+			category:            res.category.Id(),
+			attrs:               res.attrs,
+			interceptActivation: res.interceptActivation,
+		}
+	}
+	// Results not created through NewResult can not be
+	// captured, so this reply must not be cached.
+	return result, nil
+}
+
+func (reply *cachingSearchReply) Push(result ResultSetter) error {
+	return reply.PushAll(result)
+}
+
+func (reply *cachingSearchReply) PushAll(results ...ResultSetter) error {
+	unwrapped := make([]ResultSetter, len(results))
+	cached := make([]*cachedResult, len(results))
+	for i, result := range results {
+		unwrapped[i], cached[i] = reply.unwrap(result)
+	}
+	if err := PushResults(reply.SearchReplier, unwrapped...); err != nil {
+		return err
+	}
+	reply.lock.Lock()
+	defer reply.lock.Unlock()
+	for _, res := range cached {
+		if res == nil {
+			reply.uncacheable = true
+			continue
+		}
+		reply.entry.results = append(reply.entry.results, *res)
+	}
+	return nil
+}
+
+func (reply *cachingSearchReply) PushFilters(filters []Filter, state FilterState) error {
+	if err := reply.SearchReplier.PushFilters(filters, state); err != nil {
+		return err
+	}
+	reply.lock.Lock()
+	reply.entry.filters = &cachedFilters{filters, state}
+	reply.lock.Unlock()
+	return nil
+}
@@ -0,0 +1,159 @@
+package scopes_test
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+	"launchpad.net/go-unityscopes/v2"
+)
+
+type countingScope struct {
+	recordedScope
+	searches int
+}
+
+func (sc *countingScope) Search(query *scopes.CannedQuery, metadata *scopes.SearchMetadata, reply scopes.SearchReplier, cancelled <-chan bool) error {
+	sc.searches++
+	return sc.recordedScope.Search(query, metadata, reply, cancelled)
+}
+
+func (s *S) TestResultCache(c *C) {
+	inner := &countingScope{recordedScope: recordedScope{title: "Title"}}
+	cache := scopes.NewResultCache(time.Hour, 2)
+	scope := scopes.Chain(inner, cache.Middleware())
+	metadata := scopes.NewSearchMetadata(0, "us", "phone")
+
+	reply := &testSearchReply{}
+	c.Check(scope.Search(scopes.NewCannedQuery("scope", "", ""), metadata, reply, nil), IsNil)
+	c.Check(inner.searches, Equals, 1)
+	c.Check(cache.Len(), Equals, 1)
+
+	// The second search is answered from the cache
+	reply = &testSearchReply{}
+	c.Check(scope.Search(scopes.NewCannedQuery("scope", "", ""), metadata, reply, nil), IsNil)
+	c.Check(inner.searches, Equals, 1)
+	c.Check(reply.categories, DeepEquals, []string{"cat"})
+	c.Check(reply.titles, DeepEquals, []string{"Title"})
+
+	// A different query, department or locale misses
+	c.Check(scope.Search(scopes.NewCannedQuery("scope", "foo", ""), metadata, &testSearchReply{}, nil), IsNil)
+	c.Check(inner.searches, Equals, 2)
+	c.Check(scope.Search(scopes.NewCannedQuery("scope", "", "dept"), metadata, &testSearchReply{}, nil), IsNil)
+	c.Check(inner.searches, Equals, 3)
+	c.Check(scope.Search(scopes.NewCannedQuery("scope", "", ""), scopes.NewSearchMetadata(0, "de", "phone"), &testSearchReply{}, nil), IsNil)
+	c.Check(inner.searches, Equals, 4)
+
+	// Only the two most recently used replies are kept
+	c.Check(cache.Len(), Equals, 2)
+
+	cache.Purge()
+	c.Check(cache.Len(), Equals, 0)
+	c.Check(scope.Search(scopes.NewCannedQuery("scope", "", ""), metadata, &testSearchReply{}, nil), IsNil)
+	c.Check(inner.searches, Equals, 5)
+}
+
+func (s *S) TestResultCacheExpiry(c *C) {
+	inner := &countingScope{recordedScope: recordedScope{title: "Title"}}
+	cache := scopes.NewResultCache(10*time.Millisecond, 10)
+	scope := scopes.Chain(inner, cache.Middleware())
+	query := scopes.NewCannedQuery("scope", "", "")
+	metadata := scopes.NewSearchMetadata(0, "us", "phone")
+
+	c.Check(scope.Search(query, metadata, &testSearchReply{}, nil), IsNil)
+	time.Sleep(20 * time.Millisecond)
+	c.Check(scope.Search(query, metadata, &testSearchReply{}, nil), IsNil)
+	c.Check(inner.searches, Equals, 2)
+}
+
+func (s *S) TestResultCacheSkipsFailedAndCancelled(c *C) {
+	inner := &countingScope{recordedScope: recordedScope{title: "Title"}}
+	cache := scopes.NewResultCache(time.Hour, 10)
+	scope := scopes.Chain(inner, cache.Middleware())
+	metadata := scopes.NewSearchMetadata(0, "us", "phone")
+
+	c.Check(scope.Search(scopes.NewCannedQuery("scope", "fail", ""), metadata, &testSearchReply{}, nil), NotNil)
+	c.Check(cache.Len(), Equals, 0)
+
+	cancelled := make(chan bool)
+	close(cancelled)
+	c.Check(scope.Search(scopes.NewCannedQuery("scope", "", ""), metadata, &testSearchReply{}, cancelled), IsNil)
+	c.Check(cache.Len(), Equals, 0)
+}
+
+type purgingScope struct {
+	countingScope
+	cache *scopes.ResultCache
+}
+
+func (sc *purgingScope) Search(query *scopes.CannedQuery, metadata *scopes.SearchMetadata, reply scopes.SearchReplier, cancelled <-chan bool) error {
+	err := sc.countingScope.Search(query, metadata, reply, cancelled)
+	sc.cache.Purge()
+	return err
+}
+
+func (s *S) TestResultCachePurgeDuringSearch(c *C) {
+	cache := scopes.NewResultCache(time.Hour, 10)
+	inner := &purgingScope{countingScope: countingScope{recordedScope: recordedScope{title: "Title"}}, cache: cache}
+	scope := scopes.Chain(inner, cache.Middleware())
+	query := scopes.NewCannedQuery("scope", "", "")
+	metadata := scopes.NewSearchMetadata(0, "us", "phone")
+
+	// The reply was built before the purge, so it is not cached
+	c.Check(scope.Search(query, metadata, &testSearchReply{}, nil), IsNil)
+	c.Check(cache.Len(), Equals, 0)
+	c.Check(scope.Search(query, metadata, &testSearchReply{}, nil), IsNil)
+	c.Check(inner.searches, Equals, 2)
+}
+
+func (s *S) TestResultCacheCacheable(c *C) {
+	inner := &countingScope{recordedScope: recordedScope{title: "Title"}}
+	cache := scopes.NewResultCache(time.Hour, 10)
+	cache.Cacheable = func(query *scopes.CannedQuery, metadata *scopes.SearchMetadata) bool {
+		return query.QueryString() == ""
+	}
+	scope := scopes.Chain(inner, cache.Middleware())
+	metadata := scopes.NewSearchMetadata(0, "us", "phone")
+
+	c.Check(scope.Search(scopes.NewCannedQuery("scope", "foo", ""), metadata, &testSearchReply{}, nil), IsNil)
+	c.Check(scope.Search(scopes.NewCannedQuery("scope", "foo", ""), metadata, &testSearchReply{}, nil), IsNil)
+	c.Check(inner.searches, Equals, 2)
+	c.Check(cache.Len(), Equals, 0)
+}
+
+func (s *S) TestResultCacheConcurrentSearches(c *C) {
+	cache := scopes.NewResultCache(time.Hour, 2)
+	scope := scopes.Chain(&recordedScope{title: "Title"}, cache.Middleware())
+	metadata := scopes.NewSearchMetadata(0, "us", "phone")
+
+	// Run with -race to check searches can share the cache while
+	// it is purged
+	errs := make(chan error, 8*20)
+	var wait sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			for j := 0; j < 20; j++ {
+				if (i+j)%5 == 0 {
+					cache.Purge()
+					continue
+				}
+				reply := &testSearchReply{}
+				if err := scope.Search(scopes.NewCannedQuery("scope", fmt.Sprint(j%3), ""), metadata, reply, nil); err != nil {
+					errs <- err
+				} else if len(reply.titles) != 1 {
+					errs <- fmt.Errorf("expected one result, got %q", reply.titles)
+				}
+			}
+		}(i)
+	}
+	wait.Wait()
+	close(errs)
+
+	for err := range errs {
+		c.Check(err, IsNil)
+	}
+	c.Check(cache.Len() <= 2, Equals, true)
+}
@@ -0,0 +1,90 @@
+package scopes_test
+
+import (
+	"context"
+	"time"
+
+	. "gopkg.in/check.v1"
+	"launchpad.net/go-unityscopes/v2"
+)
+
+func (s *S) TestQueryContextCancel(c *C) {
+	cancel := make(chan bool, 1)
+	ctx, cancelled, done := scopes.NewQueryContext(cancel, 0, nil)
+	defer done()
+
+	c.Check(ctx.Err(), IsNil)
+	select {
+	case <-cancelled:
+		c.Fatal("query cancelled early")
+	default:
+	}
+
+	cancel <- true
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		c.Fatal("cancelled channel was not closed")
+	}
+	c.Check(ctx.Err(), Equals, context.Canceled)
+
+	// The channel stays readable for every watcher
+	_, ok := <-cancelled
+	c.Check(ok, Equals, false)
+}
+
+func (s *S) TestQueryContextDone(c *C) {
+	cancel := make(chan bool, 1)
+	ctx, cancelled, done := scopes.NewQueryContext(cancel, 0, nil)
+	done()
+
+	<-cancelled
+	c.Check(ctx.Err(), Equals, context.Canceled)
+}
+
+func (s *S) TestQueryContextDeadline(c *C) {
+	cancel := make(chan bool, 1)
+	deadline := make(chan bool)
+	ctx, cancelled, done := scopes.NewQueryContext(cancel, 10*time.Millisecond, func() {
+		close(deadline)
+	})
+	defer done()
+
+	_, ok := ctx.Deadline()
+	c.Check(ok, Equals, true)
+	select {
+	case <-deadline:
+	case <-time.After(5 * time.Second):
+		c.Fatal("deadline callback was not called")
+	}
+	<-cancelled
+	c.Check(ctx.Err(), Equals, context.DeadlineExceeded)
+}
+
+func (s *S) TestQueryContextDeadlineNotReached(c *C) {
+	cancel := make(chan bool, 1)
+	ctx, cancelled, done := scopes.NewQueryContext(cancel, time.Hour, func() {
+		c.Error("deadline callback called after query completed")
+	})
+	done()
+
+	<-cancelled
+	c.Check(ctx.Err(), Equals, context.Canceled)
+}
+
+func (s *S) TestMetadataContext(c *C) {
+	metadata := scopes.NewSearchMetadata(2, "us", "phone")
+	c.Check(metadata.Context(), Equals, context.Background())
+
+	actionMetadata := scopes.NewActionMetadata("us", "phone")
+	c.Check(actionMetadata.Context(), Equals, context.Background())
+}
+
+func (s *S) TestMetadataRemaining(c *C) {
+	metadata := scopes.NewSearchMetadata(2, "us", "phone")
+	_, ok := metadata.Deadline()
+	c.Check(ok, Equals, false)
+	remaining, ok := metadata.Remaining()
+	c.Check(ok, Equals, false)
+	c.Check(remaining, Equals, time.Duration(0))
+}
@@ -1,3 +1,5 @@
+// +build cgo,!purego
+
 #include <unity/scopes/ColumnLayout.h>
 #include <unity/scopes/Variant.h>
 
@@ -34,7 +36,7 @@ void column_layout_add_column(_ColumnLayout *layout, void *gostring_array_widget
     try {
         reinterpret_cast<ColumnLayout*>(layout)->add_column(api_widgets);
     } catch(unity::LogicException & e) {
-        *error = strdup(e.what());
+        *error = exception_string(e);
     }
 }
 
@@ -57,7 +59,7 @@ void *column_layout_column(_ColumnLayout *layout, int column, int *length, char
         return as_bytes(json_data, length);
 
     } catch(const std::exception & e) {
-        *error = strdup(e.what());
+        *error = exception_string(e);
         return nullptr;
     }
 }
@@ -1,3 +1,5 @@
+// +build cgo,!purego
+
 package scopes
 
 // #include <stdlib.h>
@@ -20,14 +22,26 @@ type ColumnLayout struct {
 func finalizeColumnLayout(layout *ColumnLayout) {
 	if layout.c != nil {
 		C.destroy_column_layout(layout.c)
+		untrackObject()
 	}
 	layout.c = nil
 }
 
+// Close releases the native layout held by this ColumnLayout.
+//
+// It is safe to call Close more than once, and the layout must not
+// be used afterwards.  If Close is not called, the layout is
+// released when the ColumnLayout is garbage collected.
+func (layout *ColumnLayout) Close() error {
+	finalizeColumnLayout(layout)
+	return nil
+}
+
 func makeColumnLayout(c *C._ColumnLayout) *ColumnLayout {
 	layout := new(ColumnLayout)
 	runtime.SetFinalizer(layout, finalizeColumnLayout)
 	layout.c = c
+	trackObject()
 	return layout
 }
 
@@ -76,5 +90,5 @@ func (layout *ColumnLayout) Column(column int) ([]string, error) {
 	var value []string
 	err := json.Unmarshal(C.GoBytes(data, length), &value)
 
-	return value, err
+	return value, serializationError(err)
 }
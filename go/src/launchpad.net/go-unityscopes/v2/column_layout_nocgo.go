@@ -0,0 +1,57 @@
+// +build !cgo purego
+
+package scopes
+
+import (
+	"fmt"
+)
+
+// ColumnLayout is used represent different representations of a widget.
+// Depending on the device your applications runs you can have several predefined
+// column layouts in order to represent your view in the way it fits better the
+// aspect ratio.
+type ColumnLayout struct {
+	numColumns int
+	columns    [][]string
+}
+
+// Close releases the native layout held by this ColumnLayout.
+//
+// Without cgo there is nothing to release, so Close does nothing.
+func (layout *ColumnLayout) Close() error {
+	return nil
+}
+
+// NewColumnLayout Creates a layout definition that expects num_of_columns columns to be added with ColumnLayout.AddColumn.
+func NewColumnLayout(num_columns int) *ColumnLayout {
+	return &ColumnLayout{numColumns: num_columns}
+}
+
+// AddColumn adds a new column and assigns widgets to it.
+// ColumnLayout expects exactly the number of columns passed to the constructor to be created with the
+// AddColumn method.
+func (layout *ColumnLayout) AddColumn(widgetIds ...string) error {
+	if len(layout.columns) >= layout.numColumns {
+		return newError(ErrLogic, fmt.Sprintf("ColumnLayout::add_column(): number of columns exceeded, expected %d columns", layout.numColumns))
+	}
+	layout.columns = append(layout.columns, append([]string{}, widgetIds...))
+	return nil
+}
+
+// NumberOfColumns gets the number of columns expected by this layout as specified in the constructor.
+func (layout *ColumnLayout) NumberOfColumns() int {
+	return layout.numColumns
+}
+
+// Size gets the current number of columns in this layout.
+func (layout *ColumnLayout) Size() int {
+	return len(layout.columns)
+}
+
+// Column retrieves the list of widgets for given column.
+func (layout *ColumnLayout) Column(column int) ([]string, error) {
+	if column < 0 || column >= len(layout.columns) {
+		return nil, newError(ErrInvalidArgument, fmt.Sprintf("ColumnLayout::column(): invalid column index %d, layout size is %d", column, len(layout.columns)))
+	}
+	return append([]string{}, layout.columns[column]...), nil
+}
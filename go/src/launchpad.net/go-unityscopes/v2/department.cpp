@@ -1,3 +1,5 @@
+// +build cgo,!purego
+
 #include <stdexcept>
 #include <cstring>
 
@@ -32,7 +34,7 @@ void new_department(void *dept_id, _CannedQuery *query, void *label, SharedPtrDa
         }
         init_ptr<Department>(dept, std::move(d));
     } catch (const std::exception &e) {
-        *error = strdup(e.what());
+        *error = exception_string(e);
     }
 }
 
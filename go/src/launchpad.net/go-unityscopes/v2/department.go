@@ -1,3 +1,5 @@
+// +build cgo,!purego
+
 package scopes
 
 // #include <stdlib.h>
@@ -29,11 +31,25 @@ func NewDepartment(departmentID string, query *CannedQuery, label string) (*Depa
 	if err := checkError(errorString); err != nil {
 		return nil, err
 	}
+	trackObject()
 	return dept, nil
 }
 
 func finalizeDepartment(dept *Department) {
-	C.destroy_department_ptr(&dept.d[0])
+	if dept.d[0] != 0 || dept.d[1] != 0 {
+		C.destroy_department_ptr(&dept.d[0])
+		untrackObject()
+	}
+}
+
+// Close releases the native department held by this Department.
+//
+// It is safe to call Close more than once, and the department must not
+// be used afterwards.  If Close is not called, the department is
+// released when the Department is garbage collected.
+func (dept *Department) Close() error {
+	finalizeDepartment(dept)
+	return nil
 }
 
 // AddSubdepartment adds a new child department to this department.
@@ -120,6 +136,7 @@ func (dept *Department) Subdepartments() []*Department {
 	for i := 0; i < length; i++ {
 		ptr_depts[i] = makeDepartment()
 		ptr_depts[i].d = slice[i]
+		trackObject()
 	}
 	return ptr_depts
 }
@@ -0,0 +1,103 @@
+// +build !cgo purego
+
+package scopes
+
+// Department represents a section of the a scope's results.  A
+// department can have sub-departments.
+type Department struct {
+	id                string
+	label             string
+	alternateLabel    string
+	query             *CannedQuery
+	subdepartments    []*Department
+	hasSubdepartments bool
+}
+
+// NewDepartment creates a new department using the given canned query.
+func NewDepartment(departmentID string, query *CannedQuery, label string) (*Department, error) {
+	if label == "" {
+		return nil, newError(ErrInvalidArgument, "Department(): Invalid empty label string")
+	}
+	deptQuery := *query
+	deptQuery.filterState = query.FilterState()
+	deptQuery.SetDepartmentID(departmentID)
+	return &Department{
+		id:    departmentID,
+		label: label,
+		query: &deptQuery,
+	}, nil
+}
+
+// Close releases the native department held by this Department.
+//
+// Without cgo there is nothing to release, so Close does nothing.
+func (dept *Department) Close() error {
+	return nil
+}
+
+// AddSubdepartment adds a new child department to this department.
+func (dept *Department) AddSubdepartment(child *Department) {
+	dept.subdepartments = append(dept.subdepartments, child)
+}
+
+// Id gets the identifier of this department.
+func (dept *Department) Id() string {
+	return dept.id
+}
+
+// Label gets the label of this department.
+func (dept *Department) Label() string {
+	return dept.label
+}
+
+// Query gets the canned query associated with this department.
+func (dept *Department) Query() *CannedQuery {
+	query := *dept.query
+	query.filterState = dept.query.FilterState()
+	return &query
+}
+
+// SetAlternateLabel sets the alternate label for this department.
+//
+// This should express the plural form of the normal label.  For
+// example, if the normal label is "Books", then the alternate label
+// should be "All Books".
+//
+// The alternate label only needs to be provided for the current
+// department.
+func (dept *Department) SetAlternateLabel(label string) {
+	dept.alternateLabel = label
+}
+
+// AlternateLabel gets the alternate label for this department.
+//
+// This should express the plural form of the normal label.  For
+// example, if the normal label is "Books", then the alternate label
+// should be "All Books".
+func (dept *Department) AlternateLabel() string {
+	return dept.alternateLabel
+}
+
+// SetHasSubdepartments sets whether this department has subdepartments.
+//
+// It is not necessary to call this if AddSubdepartment has been
+// called.  It intended for cases where subdepartments have not been
+// specified but the shell should still act as if it has them.
+func (dept *Department) SetHasSubdepartments(subdepartments bool) {
+	dept.hasSubdepartments = subdepartments
+}
+
+// HasSubdepartments checks if this department has subdepartments or has_subdepartments flag is set
+func (dept *Department) HasSubdepartments() bool {
+	return dept.hasSubdepartments || len(dept.subdepartments) > 0
+}
+
+// Subdepartments gets list of sub-departments of this department.
+func (dept *Department) Subdepartments() []*Department {
+	return append([]*Department{}, dept.subdepartments...)
+}
+
+// SetSubdepartments sets sub-departments of this department.
+func (dept *Department) SetSubdepartments(subdepartments []*Department) {
+	dept.subdepartments = append([]*Department(nil), subdepartments...)
+}
@@ -18,9 +18,9 @@ left blank.
 The shell may ask the scope for search results, which will cause the
 Search method to be invoked:
 
-    func (s *MyScope) Search(query *scopes.CannedQuery, metadata *scopes.SearchMetadata, reply *scopes.SearchReply, cancelled <-chan bool) error {
+    func (s *MyScope) Search(query *scopes.CannedQuery, metadata *scopes.SearchMetadata, reply scopes.SearchReplier, cancelled <-chan bool) error {
         category := reply.RegisterCategory("cat_id", "category", "", "")
-        result := scopes.NewCategorisedResult(category)
+        result := reply.NewResult(category)
         result.SetTitle("Result for " + query.QueryString())
         reply.Push(result)
         return nil
@@ -30,16 +30,23 @@ In general, scopes will:
 
 * Register result categories via reply.RegisterCategory()
 
-* Create new results via NewCategorisedResult(), and push them with reply.Push(result)
+* Create new results via reply.NewResult(), and push them with reply.Push(result)
+or, to push many results with a single call into the scopes runtime,
+scopes.PushResults(reply, results...)
 
-* Check for cancellation requests via the provided channel.
+* Check for cancellation requests via the provided channel, which is
+closed when the query is cancelled.  The same cancellation is available
+as a context.Context through metadata.Context().  If a query timeout
+is set with SetQueryTimeout or --query-timeout, the request is also
+cancelled at its deadline, and metadata.Remaining() reports the time
+budget left.
 
 The Search method will be invoked with an empty query when sufacing
 results are wanted.
 
 The shell may ask the scope to provide a preview of a result, which causes the Preview method to be invoked:
 
-    func (s *MyScope) Preview(result *scopes.Result, metadata *scopes.ActionMetadata, reply *scopes.PreviewReply, cancelled <-chan bool) error {
+    func (s *MyScope) Preview(result *scopes.Result, metadata *scopes.ActionMetadata, reply scopes.PreviewReplier, cancelled <-chan bool) error {
         widget := scopes.NewPreviewWidget("foo", "text")
         widget.AddAttributeValue("text", "Hello")
         reply.PushWidgets(widget)
@@ -62,6 +69,50 @@ respond to, the scope should implement the PerformAction method:
 The PerformAction method is not part of the main Scope interface, so
 the feature need only be implemented for scopes that use the feature.
 
+Search and Preview receive their replies as the SearchReplier and
+PreviewReplier interfaces, so scope logic can also be driven by
+alternative implementations such as test doubles.
+
+Cross-cutting behaviour such as logging or timing can be added by
+wrapping the scope in Middleware with Chain:
+
+    scopes.Run(scopes.Chain(&MyScope{}, scopes.LoggingMiddleware(nil)))
+
+Repeated searches can be answered without calling Search again by
+adding a ResultCache's Middleware to the chain.
+
+Running a scope with --record or the GO_UNITYSCOPES_RECORD environment
+variable set to a file name appends a JSON lines recording of every
+request and reply to that file.  Replay runs a recording against a
+scope, and DiffRecordings compares the replies, which makes recordings
+usable as golden test data.
+
+A panic in Search, Preview, Activate or PerformAction is recovered and
+logged with its stack trace, and reported to the shell as a
+*PanicError.  Running the scope with --debug-panics re-panics after
+logging instead.
+
+Errors returned by the package can be classified with errors.Is against
+ErrInvalidArgument, ErrLogic, ErrSerialization, ErrReplyClosed and the
+other Err* variables, and errors reported by the scopes runtime can be
+inspected with errors.As as an *Error, which names the C++ exception
+class.  Pushing to a reply after its query has finished or been
+cancelled returns ErrReplyClosed.
+
+Objects backed by the scopes runtime, such as CannedQuery, Result and
+Department, are released when they are garbage collected.  Scopes that
+create many of them can release them earlier with their Close methods,
+and tests can check for leaks with LiveNativeObjects.
+
+When built with CGO_ENABLED=0 or the purego build tag, the package does
+not use the scopes runtime: queries, metadata, results, departments and
+layouts are kept in Go memory, and Run can only write the settings
+definition.  Together with the fake replies in the scopestest package,
+this lets a scope's tests run without libunity-scopes.  The purego tag
+keeps cgo enabled, so the tests can also run with the race detector:
+
+    go test -race -tags purego
+
 Finally, the scope can be exported in the main function:
 
     func main() {
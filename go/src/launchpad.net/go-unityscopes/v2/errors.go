@@ -0,0 +1,119 @@
+package scopes
+
+import (
+	"errors"
+	"strings"
+)
+
+// These errors classify the failures reported by the bindings and the
+// scopes runtime.  Errors returned by this package can be checked
+// against them with errors.Is.
+var (
+	// ErrInvalidArgument is returned when a value passed to the
+	// scopes runtime is rejected.
+	ErrInvalidArgument = errors.New("Invalid argument")
+
+	// ErrLogic is returned when an operation is not valid in the
+	// current state, such as registering a preview layout twice.
+	ErrLogic = errors.New("Logic error")
+
+	// ErrSerialization is returned when a value can not be converted
+	// to or from JSON.
+	ErrSerialization = errors.New("Serialization error")
+
+	// ErrReplyClosed is returned when pushing to a reply whose query
+	// has finished or been cancelled.  Scopes will usually want to
+	// stop searching when they see it, but it is not a failure of
+	// the scope.
+	ErrReplyClosed = errors.New("Reply is finished or cancelled")
+
+	// ErrNotFound is returned when the scopes runtime can not find a
+	// requested scope or object.
+	ErrNotFound = errors.New("Not found")
+
+	// ErrResource is returned when a file or other system resource
+	// can not be used.
+	ErrResource = errors.New("Resource error")
+
+	// ErrMiddleware is returned when communication with the scopes
+	// middleware fails.
+	ErrMiddleware = errors.New("Middleware error")
+
+	// ErrNoRuntime is returned by Run when the package was built
+	// without cgo, and so without the scopes runtime.
+	ErrNoRuntime = errors.New("The scopes runtime is not available without cgo")
+)
+
+// exceptionKinds maps C++ exception classes to the error kind they are
+// reported as.
+var exceptionKinds = map[string]error{
+	"std::invalid_argument":                    ErrInvalidArgument,
+	"std::out_of_range":                        ErrInvalidArgument,
+	"unity::InvalidArgumentException":          ErrInvalidArgument,
+	"std::logic_error":                         ErrLogic,
+	"unity::LogicException":                    ErrLogic,
+	"unity::ShutdownException":                 ErrReplyClosed,
+	"unity::scopes::ObjectNotExistException":   ErrReplyClosed,
+	"unity::scopes::NotFoundException":         ErrNotFound,
+	"unity::ResourceException":                 ErrResource,
+	"unity::FileException":                     ErrResource,
+	"unity::SyscallException":                  ErrResource,
+	"unity::scopes::ConfigException":           ErrResource,
+	"unity::scopes::MiddlewareException":       ErrMiddleware,
+	"unity::scopes::TimeoutException":          ErrMiddleware,
+	"unity::scopes::DeadlineException":         ErrMiddleware,
+	"unity::scopes::RegistryException":         ErrMiddleware,
+	"unity::scopes::RuntimeDestroyedException": ErrMiddleware,
+}
+
+// Error describes a failure reported by the scopes runtime or the
+// bindings.
+//
+// Kind is one of the Err* variables above, or nil if the failure
+// could not be classified.  errors.Is matches an *Error against its
+// Kind, and errors.As can be used to inspect the other fields.
+type Error struct {
+	Kind error
+	// Exception is the C++ exception class that reported the
+	// failure, or empty if it was detected by the bindings.
+	Exception string
+	Message   string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the kind of the error.
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// newError creates an error of the given kind detected by the
+// bindings.
+func newError(kind error, message string) error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// serializationError wraps a failure to convert a value to or from
+// JSON.
+func serializationError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: ErrSerialization, Message: err.Error()}
+}
+
+// parseError converts an error string produced by exception_string on
+// the C++ side into an *Error.
+func parseError(s string) error {
+	exception, message := "", s
+	if i := strings.IndexByte(s, '\x1f'); i >= 0 {
+		exception, message = s[:i], s[i+1:]
+	}
+	return &Error{
+		Kind:      exceptionKinds[exception],
+		Exception: exception,
+		Message:   message,
+	}
+}
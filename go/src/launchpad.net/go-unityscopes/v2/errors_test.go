@@ -0,0 +1,54 @@
+package scopes_test
+
+import (
+	"errors"
+
+	. "gopkg.in/check.v1"
+	"launchpad.net/go-unityscopes/v2"
+)
+
+func (s *S) TestParseError(c *C) {
+	err := scopes.ParseError("unity::InvalidArgumentException\x1funity::InvalidArgumentException: bad value")
+	c.Check(err, ErrorMatches, "unity::InvalidArgumentException: bad value")
+	c.Check(errors.Is(err, scopes.ErrInvalidArgument), Equals, true)
+	c.Check(errors.Is(err, scopes.ErrLogic), Equals, false)
+
+	var scopesErr *scopes.Error
+	c.Assert(errors.As(err, &scopesErr), Equals, true)
+	c.Check(scopesErr.Exception, Equals, "unity::InvalidArgumentException")
+	c.Check(scopesErr.Kind, Equals, scopes.ErrInvalidArgument)
+
+	c.Check(errors.Is(scopes.ParseError("std::logic_error\x1foops"), scopes.ErrLogic), Equals, true)
+	c.Check(errors.Is(scopes.ParseError("unity::scopes::ObjectNotExistException\x1fgone"), scopes.ErrReplyClosed), Equals, true)
+	c.Check(errors.Is(scopes.ParseError("unity::scopes::TimeoutException\x1fslow"), scopes.ErrMiddleware), Equals, true)
+
+	// Unknown exceptions and untagged messages are reported without a kind
+	err = scopes.ParseError("std::bad_alloc\x1fout of memory")
+	c.Check(err, ErrorMatches, "out of memory")
+	c.Assert(errors.As(err, &scopesErr), Equals, true)
+	c.Check(scopesErr.Kind, IsNil)
+	err = scopes.ParseError("plain message")
+	c.Check(err, ErrorMatches, "plain message")
+	c.Assert(errors.As(err, &scopesErr), Equals, true)
+	c.Check(scopesErr.Exception, Equals, "")
+}
+
+func (s *S) TestRuntimeErrorKinds(c *C) {
+	// The scopes runtime rejects unknown attributes
+	var value string
+	err := scopes.NewTestingResult().Get("missing", &value)
+	c.Check(errors.Is(err, scopes.ErrInvalidArgument), Equals, true)
+}
+
+func (s *S) TestBindingErrorKinds(c *C) {
+	res := scopes.NewResultBuilder(scopes.NewCategory("apps", "Apps", "", ""))
+	var value string
+	c.Check(errors.Is(res.Get("missing", &value), scopes.ErrInvalidArgument), Equals, true)
+	c.Check(errors.Is(res.Set("bad", &unserializable{}), scopes.ErrSerialization), Equals, true)
+	c.Check(errors.Is(scopes.NewTestingResult().Set("bad", &unserializable{}), scopes.ErrSerialization), Equals, true)
+
+	err := scopes.BuildTestingResults([]scopes.ResultSetter{res})
+	c.Check(errors.Is(err, scopes.ErrInvalidArgument), Equals, true)
+	err = scopes.BuildTestingResults([]scopes.ResultSetter{scopes.NewTestingResult()})
+	c.Check(errors.Is(err, scopes.ErrInvalidArgument), Equals, true)
+}
@@ -1,7 +1,9 @@
 package scopes
 
 import (
+	"context"
 	"encoding/json"
+	"time"
 )
 
 // This file exports certain private functions for use by tests.
@@ -18,3 +20,29 @@ func NewTestingScopeMetadata(json_data string) ScopeMetadata {
 
 	return scopeMetadata
 }
+
+func NewQueryContext(cancel <-chan bool, timeout time.Duration, onDeadline func()) (context.Context, <-chan bool, context.CancelFunc) {
+	return newQueryContext(cancel, timeout, onDeadline)
+}
+
+func CallRecovered(callback string, f func() error) error {
+	return callRecovered(callback, f)
+}
+
+func SetDebugPanics(debug bool) (old bool) {
+	old = *debugPanics
+	*debugPanics = debug
+	return
+}
+
+func NewTestingCategory(id string) *Category {
+	return newTestingCategory(id)
+}
+
+func BuildTestingResults(results []ResultSetter) error {
+	return buildTestingResults(results)
+}
+
+func ParseError(s string) error {
+	return parseError(s)
+}
@@ -1,3 +1,5 @@
+// +build cgo,!purego
+
 #include "helpers.h"
 
 #include <cstdlib>
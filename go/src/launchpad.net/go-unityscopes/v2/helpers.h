@@ -1,13 +1,21 @@
 #ifndef UNITYSCOPE_HELPERS_H
 #define UNITYSCOPE_HELPERS_H
 
+#include <exception>
 #include <string>
+#include <vector>
+
+#include <unity/scopes/CategorisedResult.h>
+
+#include "shim.h"
 
 namespace gounityscopes {
 namespace internal {
 
 std::string from_gostring(void *str);
 void *as_bytes(const std::string &str, int *length);
+char *exception_string(const std::exception &e);
+std::vector<unity::scopes::CategorisedResult> build_results(SharedPtrData *categories, void *results_json, int count);
 
 }
 }
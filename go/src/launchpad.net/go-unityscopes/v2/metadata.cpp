@@ -1,3 +1,5 @@
+// +build cgo,!purego
+
 #include <stdexcept>
 #include <cmath>
 #include <cstring>
@@ -84,7 +86,7 @@ void search_metadata_set_location(_SearchMetadata *metadata, char *json_data, in
         Location location(value.get_dict());
         reinterpret_cast<SearchMetadata*>(metadata)->set_location(location);
     } catch (const std::exception & e) {
-        *error = strdup(e.what());
+        *error = exception_string(e);
     }
 }
 
@@ -97,7 +99,7 @@ void search_metadata_set_aggregated_keywords(_SearchMetadata *metadata, void *go
         }
         reinterpret_cast<SearchMetadata*>(metadata)->set_aggregated_keywords(keywords);
     } catch (const std::exception & e) {
-        *error = strdup(e.what());
+        *error = exception_string(e);
     }
 }
 
@@ -134,7 +136,7 @@ void action_metadata_set_scope_data(_ActionMetadata *metadata, char *json_data,
         Variant value = Variant::deserialize_json(std::string(json_data, json_data_length));
         reinterpret_cast<ActionMetadata*>(metadata)->set_scope_data(value);
     } catch (const std::exception & e) {
-        *error = strdup(e.what());
+        *error = exception_string(e);
     }
 }
 
@@ -143,7 +145,7 @@ void action_metadata_set_hint(_ActionMetadata *metadata, void *key, char *json_d
         Variant value = Variant::deserialize_json(std::string(json_data, json_data_length));
         reinterpret_cast<ActionMetadata*>(metadata)->set_hint(from_gostring(key), value);
     } catch (const std::exception & e) {
-        *error = strdup(e.what());
+        *error = exception_string(e);
     }
 }
 
@@ -155,7 +157,7 @@ void *action_metadata_get_hint(_ActionMetadata *metadata, void *key, int *data_l
         return as_bytes(data, data_length);
     } catch (const std::exception & e) {
         *data_length = 0;
-        *error = strdup(e.what());
+        *error = exception_string(e);
         return 0;
     }
 }
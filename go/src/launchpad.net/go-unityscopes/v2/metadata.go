@@ -1,27 +1,22 @@
+// +build cgo,!purego
+
 package scopes
 
 // #include <stdlib.h>
 // #include "shim.h"
 import "C"
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"runtime"
 	"unsafe"
 )
 
-type ConnectivityStatus int
-
-const (
-	ConnectivityStatusUnknown      ConnectivityStatus = 0
-	ConnectivityStatusConnected    ConnectivityStatus = 1
-	ConnectivityStatusDisconnected ConnectivityStatus = 2
-)
-
 // queryMetadata is the base class for extra metadata passed to scopes as a part of a request.
 // This base class is not exported
 type queryMetadata struct {
-	m *C._QueryMetadata
+	m   *C._QueryMetadata
+	ctx context.Context
 }
 
 // Locale returns the expected locale for the search request.
@@ -56,14 +51,26 @@ type SearchMetadata struct {
 func finalizeSearchMetadata(metadata *SearchMetadata) {
 	if metadata.m != nil {
 		C.destroy_search_metadata((*C._SearchMetadata)(metadata.m))
+		untrackObject()
 	}
 	metadata.m = nil
 }
 
+// Close releases the native metadata held by this SearchMetadata.
+//
+// It is safe to call Close more than once, and the metadata must not
+// be used afterwards.  If Close is not called, the metadata is
+// released when the SearchMetadata is garbage collected.
+func (metadata *SearchMetadata) Close() error {
+	finalizeSearchMetadata(metadata)
+	return nil
+}
+
 func makeSearchMetadata(m *C._SearchMetadata) *SearchMetadata {
 	metadata := new(SearchMetadata)
 	runtime.SetFinalizer(metadata, finalizeSearchMetadata)
 	metadata.m = (*C._QueryMetadata)(m)
+	trackObject()
 	return metadata
 }
 
@@ -80,21 +87,6 @@ func (metadata *SearchMetadata) Cardinality() int {
 	return int(C.search_metadata_get_cardinality((*C._SearchMetadata)(metadata.m)))
 }
 
-type Location struct {
-	Latitude           float64 `json:"latitude"`
-	Longitude          float64 `json:"longitude"`
-	Altitude           float64 `json:"altitude"`
-	AreaCode           string  `json:"area_code"`
-	City               string  `json:"city"`
-	CountryCode        string  `json:"country_code"`
-	CountryName        string  `json:"country_name"`
-	HorizontalAccuracy float64 `json:"horizontal_accuracy"`
-	VerticalAccuracy   float64 `json:"vertical_accuracy"`
-	RegionCode         string  `json:"region_code"`
-	RegionName         string  `json:"region_name"`
-	ZipPostalCode      string  `json:"zip_postal_code"`
-}
-
 func (metadata *SearchMetadata) Location() *Location {
 	var length C.int
 	locData := C.search_metadata_get_location((*C._SearchMetadata)(metadata.m), &length)
@@ -125,7 +117,7 @@ func (metadata *SearchMetadata) SetLocation(l *Location) error {
 		l.ZipPostalCode}
 	data, err := json.Marshal(location)
 	if err != nil {
-		return err
+		return serializationError(err)
 	}
 	var errorString *C.char
 	C.search_metadata_set_location((*C._SearchMetadata)(metadata.m), (*C.char)(unsafe.Pointer(&data[0])), C.int(len(data)), &errorString)
@@ -164,10 +156,21 @@ type ActionMetadata struct {
 func finalizeActionMetadata(metadata *ActionMetadata) {
 	if metadata.m != nil {
 		C.destroy_action_metadata((*C._ActionMetadata)(metadata.m))
+		untrackObject()
 	}
 	metadata.m = nil
 }
 
+// Close releases the native metadata held by this ActionMetadata.
+//
+// It is safe to call Close more than once, and the metadata must not
+// be used afterwards.  If Close is not called, the metadata is
+// released when the ActionMetadata is garbage collected.
+func (metadata *ActionMetadata) Close() error {
+	finalizeActionMetadata(metadata)
+	return nil
+}
+
 // NewActionMetadata creates a new ActionMetadata with the given locale and
 // form_factor
 func NewActionMetadata(locale, form_factor string) *ActionMetadata {
@@ -179,6 +182,7 @@ func makeActionMetadata(m *C._ActionMetadata) *ActionMetadata {
 	metadata := new(ActionMetadata)
 	runtime.SetFinalizer(metadata, finalizeActionMetadata)
 	metadata.m = (*C._QueryMetadata)(m)
+	trackObject()
 	return metadata
 }
 
@@ -190,14 +194,14 @@ func (metadata *ActionMetadata) ScopeData(v interface{}) error {
 	var dataLength C.int
 	scopeData := C.action_metadata_get_scope_data((*C._ActionMetadata)(metadata.m), &dataLength)
 	defer C.free(scopeData)
-	return json.Unmarshal(C.GoBytes(scopeData, dataLength), v)
+	return serializationError(json.Unmarshal(C.GoBytes(scopeData, dataLength), v))
 }
 
 // SetScopeData attaches arbitrary data to this ActionMetadata.
 func (metadata *ActionMetadata) SetScopeData(v interface{}) error {
 	data, err := json.Marshal(v)
 	if err != nil {
-		return err
+		return serializationError(err)
 	}
 	var errorString *C.char
 	C.action_metadata_set_scope_data((*C._ActionMetadata)(metadata.m), (*C.char)(unsafe.Pointer(&data[0])), C.int(len(data)), &errorString)
@@ -208,7 +212,7 @@ func (metadata *ActionMetadata) SetScopeData(v interface{}) error {
 func (metadata *ActionMetadata) SetHint(key string, value interface{}) error {
 	data, err := json.Marshal(value)
 	if err != nil {
-		return err
+		return serializationError(err)
 	}
 	var errorString *C.char
 	C.action_metadata_set_hint((*C._ActionMetadata)(metadata.m), unsafe.Pointer(&key), (*C.char)(unsafe.Pointer(&data[0])), C.int(len(data)), &errorString)
@@ -223,7 +227,7 @@ func (metadata *ActionMetadata) Hint(key string, value interface{}) error {
 	scopeData := C.action_metadata_get_hint((*C._ActionMetadata)(metadata.m), unsafe.Pointer(&key), &dataLength, &errorString)
 	if dataLength > 0 && errorString == nil {
 		defer C.free(scopeData)
-		return json.Unmarshal(C.GoBytes(scopeData, dataLength), value)
+		return serializationError(json.Unmarshal(C.GoBytes(scopeData, dataLength), value))
 	} else {
 		return checkError(errorString)
 	}
@@ -237,65 +241,11 @@ func (metadata *ActionMetadata) Hints(value interface{}) error {
 		return nil
 	}
 	defer C.free(data)
-	return json.Unmarshal(C.GoBytes(data, length), value)
-}
-
-// we use this type to reimplement the marshaller interface in order to make values
-// like 1.0 not being converted as 1 (integer).
-type marshalFloat float64
-
-func (n marshalFloat) MarshalJSON() ([]byte, error) {
-	return []byte(fmt.Sprintf("%f", n)), nil
-}
-
-// the following structure is only used to control how the float64 types are
-// marshaled. It is not exported.
-type locationMarshal struct {
-	Latitude           marshalFloat `json:"latitude"`
-	Longitude          marshalFloat `json:"longitude"`
-	Altitude           marshalFloat `json:"altitude"`
-	AreaCode           string       `json:"area_code"`
-	City               string       `json:"city"`
-	CountryCode        string       `json:"country_code"`
-	CountryName        string       `json:"country_name"`
-	HorizontalAccuracy marshalFloat `json:"horizontal_accuracy"`
-	VerticalAccuracy   marshalFloat `json:"vertical_accuracy"`
-	RegionCode         string       `json:"region_code"`
-	RegionName         string       `json:"region_name"`
-	ZipPostalCode      string       `json:"zip_postal_code"`
-}
-
-type ProxyScopeMetadata struct {
-	Identity string `json:"identity"`
-	EndPoint string `json:"endpoint"`
-}
-
-// ScopeMetadata holds scope attributes such as name, description, icon etc.
-//
-// The information stored by ScopeMetadata comes from the .ini file for the given scope (for local scopes)
-// or is fetched from the remote server (for scopes running on Smart Scopes Server).
-// Use ListRegistryScopes from ScopeBase to get the metadata for all scopes.
-type ScopeMetadata struct {
-	m                    *C._ScopeMetadata
-	Art                  string                 `json:"art"`
-	Author               string                 `json:"author"`
-	Description          string                 `json:"description"`
-	DisplayName          string                 `json:"display_name"`
-	Icon                 string                 `json:"icon"`
-	Invisible            bool                   `json:"invisible"`
-	IsAggregator         bool                   `json:"is_aggregator"`
-	LocationDataNeeded   bool                   `json:"location_data_needed"`
-	ScopeDir             string                 `json:"scope_dir"`
-	ScopeId              string                 `json:"scope_id"`
-	Version              int                    `json:"version"`
-	Proxy                ProxyScopeMetadata     `json:"proxy"`
-	AppearanceAttributes map[string]interface{} `json:"appearance_attributes"`
-	SettingsDefinitions  []interface{}          `json:"settings_definitions"`
-	Keywords             []string               `json:"keywords"`
+	return serializationError(json.Unmarshal(C.GoBytes(data, length), value))
 }
 
 func finalizeScopeMetadata(metadata *ScopeMetadata) {
-	C.destroy_scope_metadata_ptr(metadata.m)
+	C.destroy_scope_metadata_ptr((*C._ScopeMetadata)(metadata.m))
 }
 
 func makeScopeMetadata(m *C._ScopeMetadata, json_data string) *ScopeMetadata {
@@ -303,7 +253,7 @@ func makeScopeMetadata(m *C._ScopeMetadata, json_data string) *ScopeMetadata {
 	if err := json.Unmarshal([]byte(json_data), &metadata); err != nil {
 		panic(err)
 	}
-	metadata.m = m
+	metadata.m = unsafe.Pointer(m)
 	runtime.SetFinalizer(metadata, finalizeScopeMetadata)
 	return metadata
 }
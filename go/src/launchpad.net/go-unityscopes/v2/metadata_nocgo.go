@@ -0,0 +1,166 @@
+// +build !cgo purego
+
+package scopes
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// queryMetadata is the base class for extra metadata passed to scopes as a part of a request.
+// This base class is not exported
+type queryMetadata struct {
+	locale       string
+	formFactor   string
+	connectivity ConnectivityStatus
+	ctx          context.Context
+}
+
+// Locale returns the expected locale for the search request.
+func (metadata *queryMetadata) Locale() string {
+	return metadata.locale
+}
+
+// FormFactor returns the form factor for the search request.
+func (metadata *queryMetadata) FormFactor() string {
+	return metadata.formFactor
+}
+
+// SetInternetConnectivity indicates the internet connectivity status.
+func (metadata *queryMetadata) SetInternetConnectivity(status ConnectivityStatus) {
+	metadata.connectivity = status
+}
+
+// InternetConnectivity gets internet connectivity status.
+func (metadata *queryMetadata) InternetConnectivity() ConnectivityStatus {
+	return metadata.connectivity
+}
+
+// SearchMetadata holds additional metadata about the search request.
+type SearchMetadata struct {
+	queryMetadata
+	cardinality int
+	location    *Location
+	keywords    []string
+}
+
+// Close releases the native metadata held by this SearchMetadata.
+//
+// Without cgo there is nothing to release, so Close does nothing.
+func (metadata *SearchMetadata) Close() error {
+	return nil
+}
+
+// NewSearchMetadata creates a new SearchMetadata with the given locale and
+// form_factor
+func NewSearchMetadata(cardinality int, locale, form_factor string) *SearchMetadata {
+	return &SearchMetadata{
+		queryMetadata: queryMetadata{locale: locale, formFactor: form_factor},
+		cardinality:   cardinality,
+	}
+}
+
+// Cardinality returns the desired number of results for the search request.
+func (metadata *SearchMetadata) Cardinality() int {
+	return metadata.cardinality
+}
+
+func (metadata *SearchMetadata) Location() *Location {
+	if metadata.location == nil {
+		return nil
+	}
+	location := *metadata.location
+	return &location
+}
+
+// SetLocation sets the location
+func (metadata *SearchMetadata) SetLocation(l *Location) error {
+	location := *l
+	metadata.location = &location
+	return nil
+}
+
+func (metadata *SearchMetadata) SetAggregatedKeywords(keywords []string) error {
+	metadata.keywords = append([]string(nil), keywords...)
+	return nil
+}
+
+func (metadata *SearchMetadata) AggregatedKeywords() []string {
+	return append([]string{}, metadata.keywords...)
+}
+
+func (metadata *SearchMetadata) IsAggregated() bool {
+	return len(metadata.keywords) > 0
+}
+
+// ActionMetadata holds additional metadata about the preview request
+// or result activation.
+type ActionMetadata struct {
+	queryMetadata
+	scopeData json.RawMessage
+	hints     map[string]json.RawMessage
+}
+
+// Close releases the native metadata held by this ActionMetadata.
+//
+// Without cgo there is nothing to release, so Close does nothing.
+func (metadata *ActionMetadata) Close() error {
+	return nil
+}
+
+// NewActionMetadata creates a new ActionMetadata with the given locale and
+// form_factor
+func NewActionMetadata(locale, form_factor string) *ActionMetadata {
+	return &ActionMetadata{
+		queryMetadata: queryMetadata{locale: locale, formFactor: form_factor},
+		scopeData:     json.RawMessage("null"),
+		hints:         make(map[string]json.RawMessage),
+	}
+}
+
+// ScopeData decodes the stored scope data into the given variable.
+//
+// Scope data is either set by the shell when calling a preview
+// action, or set by the scope through an ActivationResponse object.
+func (metadata *ActionMetadata) ScopeData(v interface{}) error {
+	return serializationError(json.Unmarshal(metadata.scopeData, v))
+}
+
+// SetScopeData attaches arbitrary data to this ActionMetadata.
+func (metadata *ActionMetadata) SetScopeData(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return serializationError(err)
+	}
+	metadata.scopeData = data
+	return nil
+}
+
+// SetHint sets a hint.
+func (metadata *ActionMetadata) SetHint(key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return serializationError(err)
+	}
+	metadata.hints[key] = data
+	return nil
+}
+
+// Hint returns a hint.
+// Returns error if the hint does not exist or if we got an error unmarshaling
+func (metadata *ActionMetadata) Hint(key string, value interface{}) error {
+	data, ok := metadata.hints[key]
+	if !ok {
+		return newError(ErrLogic, "unity::LogicException: QueryMetadataImpl::hint(): requested key "+key+" doesn't exist")
+	}
+	return serializationError(json.Unmarshal(data, value))
+}
+
+// Hints gets all hints.
+func (metadata *ActionMetadata) Hints(value interface{}) error {
+	data, err := json.Marshal(metadata.hints)
+	if err != nil {
+		return serializationError(err)
+	}
+	return serializationError(json.Unmarshal(data, value))
+}
@@ -0,0 +1,294 @@
+package scopes
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SearchHandler is the signature of Scope.Search.
+type SearchHandler func(query *CannedQuery, metadata *SearchMetadata, reply SearchReplier, cancelled <-chan bool) error
+
+// PreviewHandler is the signature of Scope.Preview.
+type PreviewHandler func(result *Result, metadata *ActionMetadata, reply PreviewReplier, cancelled <-chan bool) error
+
+// ActivateHandler is the signature of Activator.Activate.
+type ActivateHandler func(result *Result, metadata *ActionMetadata) (*ActivationResponse, error)
+
+// PerformActionHandler is the signature of PerformActioner.PerformAction.
+type PerformActionHandler func(result *Result, metadata *ActionMetadata, widgetId, actionId string) (*ActivationResponse, error)
+
+// Middleware wraps the callbacks of a scope.
+//
+// Each field receives the next handler in the chain and returns a
+// handler that calls it, so code can run both before and after the
+// wrapped call, replace its arguments or results, or skip it
+// entirely.  Nil fields leave the corresponding callback untouched.
+type Middleware struct {
+	Search        func(next SearchHandler) SearchHandler
+	Preview       func(next PreviewHandler) PreviewHandler
+	Activate      func(next ActivateHandler) ActivateHandler
+	PerformAction func(next PerformActionHandler) PerformActionHandler
+}
+
+// chainedScope is the Scope returned by Chain.
+type chainedScope struct {
+	scope         Scope
+	search        SearchHandler
+	preview       PreviewHandler
+	activate      ActivateHandler
+	performAction PerformActionHandler
+}
+
+// Chain wraps a scope in the given middleware.
+//
+// The first middleware is the outermost, so its hooks run first
+// before the call and last after it.  The returned scope forwards
+// SetScopeBase, and implements Activator, PerformActioner,
+// SettingsDefiner and SettingsNotifier on behalf of the wrapped
+// scope.  If the wrapped scope does not implement Activator or
+// PerformActioner, the innermost handler returns an
+// ActivationNotHandled response.
+func Chain(scope Scope, middleware ...Middleware) Scope {
+	chain := &chainedScope{
+		scope:         scope,
+		search:        scope.Search,
+		preview:       scope.Preview,
+		activate:      notHandledActivate,
+		performAction: notHandledPerformAction,
+	}
+	if activator, ok := scope.(Activator); ok {
+		chain.activate = activator.Activate
+	}
+	if actioner, ok := scope.(PerformActioner); ok {
+		chain.performAction = actioner.PerformAction
+	}
+	for i := len(middleware) - 1; i >= 0; i-- {
+		m := middleware[i]
+		if m.Search != nil {
+			chain.search = m.Search(chain.search)
+		}
+		if m.Preview != nil {
+			chain.preview = m.Preview(chain.preview)
+		}
+		if m.Activate != nil {
+			chain.activate = m.Activate(chain.activate)
+		}
+		if m.PerformAction != nil {
+			chain.performAction = m.PerformAction(chain.performAction)
+		}
+	}
+	return chain
+}
+
+func notHandledActivate(result *Result, metadata *ActionMetadata) (*ActivationResponse, error) {
+	return NewActivationResponse(ActivationNotHandled), nil
+}
+
+func notHandledPerformAction(result *Result, metadata *ActionMetadata, widgetId, actionId string) (*ActivationResponse, error) {
+	return NewActivationResponse(ActivationNotHandled), nil
+}
+
+func (chain *chainedScope) SetScopeBase(base *ScopeBase) {
+	chain.scope.SetScopeBase(base)
+}
+
+func (chain *chainedScope) Search(query *CannedQuery, metadata *SearchMetadata, reply SearchReplier, cancelled <-chan bool) error {
+	return chain.search(query, metadata, reply, cancelled)
+}
+
+func (chain *chainedScope) Preview(result *Result, metadata *ActionMetadata, reply PreviewReplier, cancelled <-chan bool) error {
+	return chain.preview(result, metadata, reply, cancelled)
+}
+
+func (chain *chainedScope) Activate(result *Result, metadata *ActionMetadata) (*ActivationResponse, error) {
+	return chain.activate(result, metadata)
+}
+
+func (chain *chainedScope) PerformAction(result *Result, metadata *ActionMetadata, widgetId, actionId string) (*ActivationResponse, error) {
+	return chain.performAction(result, metadata, widgetId, actionId)
+}
+
+// SettingsDefinition returns the wrapped scope's settings definition,
+// or nil if it does not implement SettingsDefiner.
+func (chain *chainedScope) SettingsDefinition() *SettingsDefinition {
+	if definer, ok := chain.scope.(SettingsDefiner); ok {
+		return definer.SettingsDefinition()
+	}
+	return nil
+}
+
+func (chain *chainedScope) SettingsChanged() {
+	if notifier, ok := chain.scope.(SettingsNotifier); ok {
+		notifier.SettingsChanged()
+	}
+}
+
+// LoggingMiddleware logs each callback with its arguments, duration
+// and error.  If logger is nil, the standard logger is used.
+func LoggingMiddleware(logger *log.Logger) Middleware {
+	logf := log.Printf
+	if logger != nil {
+		logf = logger.Printf
+	}
+	return Middleware{
+		Search: func(next SearchHandler) SearchHandler {
+			return func(query *CannedQuery, metadata *SearchMetadata, reply SearchReplier, cancelled <-chan bool) error {
+				start := time.Now()
+				logf("Search %q started", query.QueryString())
+				err := next(query, metadata, reply, cancelled)
+				logf("Search %q finished in %v (error: %v)", query.QueryString(), time.Since(start), err)
+				return err
+			}
+		},
+		Preview: func(next PreviewHandler) PreviewHandler {
+			return func(result *Result, metadata *ActionMetadata, reply PreviewReplier, cancelled <-chan bool) error {
+				start := time.Now()
+				logf("Preview %q started", result.URI())
+				err := next(result, metadata, reply, cancelled)
+				logf("Preview %q finished in %v (error: %v)", result.URI(), time.Since(start), err)
+				return err
+			}
+		},
+		Activate: func(next ActivateHandler) ActivateHandler {
+			return func(result *Result, metadata *ActionMetadata) (*ActivationResponse, error) {
+				start := time.Now()
+				response, err := next(result, metadata)
+				logf("Activate %q finished in %v (error: %v)", result.URI(), time.Since(start), err)
+				return response, err
+			}
+		},
+		PerformAction: func(next PerformActionHandler) PerformActionHandler {
+			return func(result *Result, metadata *ActionMetadata, widgetId, actionId string) (*ActivationResponse, error) {
+				start := time.Now()
+				response, err := next(result, metadata, widgetId, actionId)
+				logf("PerformAction %q on %q/%q finished in %v (error: %v)", result.URI(), widgetId, actionId, time.Since(start), err)
+				return response, err
+			}
+		},
+	}
+}
+
+// LatencyHistogram records how long scope callbacks take, counted in
+// fixed latency buckets.
+type LatencyHistogram struct {
+	lock    sync.Mutex
+	bounds  []time.Duration
+	buckets map[string][]int64
+}
+
+// NewLatencyHistogram creates a histogram with the given bucket upper
+// bounds.  A final bucket collects calls slower than every bound.
+func NewLatencyHistogram(bounds ...time.Duration) *LatencyHistogram {
+	sorted := append([]time.Duration(nil), bounds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return &LatencyHistogram{
+		bounds:  sorted,
+		buckets: make(map[string][]int64),
+	}
+}
+
+// Bounds returns the bucket upper bounds of the histogram.
+func (h *LatencyHistogram) Bounds() []time.Duration {
+	return append([]time.Duration(nil), h.bounds...)
+}
+
+// Observe records a call to the named callback that took the given
+// duration.
+func (h *LatencyHistogram) Observe(callback string, elapsed time.Duration) {
+	i := sort.Search(len(h.bounds), func(i int) bool { return elapsed <= h.bounds[i] })
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	counts := h.buckets[callback]
+	if counts == nil {
+		counts = make([]int64, len(h.bounds)+1)
+		h.buckets[callback] = counts
+	}
+	counts[i]++
+}
+
+// Counts returns the number of calls to the named callback
+// ("Search", "Preview", "Activate" or "PerformAction") in each
+// bucket.  The last element counts calls slower than every bound.
+func (h *LatencyHistogram) Counts(callback string) []int64 {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	counts := make([]int64, len(h.bounds)+1)
+	copy(counts, h.buckets[callback])
+	return counts
+}
+
+// Middleware returns middleware that records callback latencies in
+// the histogram.
+func (h *LatencyHistogram) Middleware() Middleware {
+	return Middleware{
+		Search: func(next SearchHandler) SearchHandler {
+			return func(query *CannedQuery, metadata *SearchMetadata, reply SearchReplier, cancelled <-chan bool) error {
+				defer h.observeSince("Search", time.Now())
+				return next(query, metadata, reply, cancelled)
+			}
+		},
+		Preview: func(next PreviewHandler) PreviewHandler {
+			return func(result *Result, metadata *ActionMetadata, reply PreviewReplier, cancelled <-chan bool) error {
+				defer h.observeSince("Preview", time.Now())
+				return next(result, metadata, reply, cancelled)
+			}
+		},
+		Activate: func(next ActivateHandler) ActivateHandler {
+			return func(result *Result, metadata *ActionMetadata) (*ActivationResponse, error) {
+				defer h.observeSince("Activate", time.Now())
+				return next(result, metadata)
+			}
+		},
+		PerformAction: func(next PerformActionHandler) PerformActionHandler {
+			return func(result *Result, metadata *ActionMetadata, widgetId, actionId string) (*ActivationResponse, error) {
+				defer h.observeSince("PerformAction", time.Now())
+				return next(result, metadata, widgetId, actionId)
+			}
+		},
+	}
+}
+
+func (h *LatencyHistogram) observeSince(callback string, start time.Time) {
+	h.Observe(callback, time.Since(start))
+}
+
+// countingSearchReply counts the results successfully pushed through
+// it.
+type countingSearchReply struct {
+	SearchReplier
+	count int64
+}
+
+func (reply *countingSearchReply) Push(result ResultSetter) error {
+	err := reply.SearchReplier.Push(result)
+	if err == nil {
+		atomic.AddInt64(&reply.count, 1)
+	}
+	return err
+}
+
+func (reply *countingSearchReply) PushAll(results ...ResultSetter) error {
+	err := PushResults(reply.SearchReplier, results...)
+	if err == nil {
+		atomic.AddInt64(&reply.count, int64(len(results)))
+	}
+	return err
+}
+
+// ResultCountMiddleware calls report with the number of results
+// pushed by each search once it has completed.
+func ResultCountMiddleware(report func(query *CannedQuery, count int)) Middleware {
+	return Middleware{
+		Search: func(next SearchHandler) SearchHandler {
+			return func(query *CannedQuery, metadata *SearchMetadata, reply SearchReplier, cancelled <-chan bool) error {
+				counter := &countingSearchReply{SearchReplier: reply}
+				err := next(query, metadata, counter, cancelled)
+				report(query, int(atomic.LoadInt64(&counter.count)))
+				return err
+			}
+		},
+	}
+}
@@ -0,0 +1,153 @@
+package scopes_test
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"time"
+
+	. "gopkg.in/check.v1"
+	"launchpad.net/go-unityscopes/v2"
+)
+
+type middlewareScope struct {
+	calls   []string
+	results int
+	err     error
+}
+
+func (sc *middlewareScope) SetScopeBase(base *scopes.ScopeBase) {
+	sc.calls = append(sc.calls, "SetScopeBase")
+}
+
+func (sc *middlewareScope) Search(query *scopes.CannedQuery, metadata *scopes.SearchMetadata, reply scopes.SearchReplier, cancelled <-chan bool) error {
+	sc.calls = append(sc.calls, "Search")
+	for i := 0; i < sc.results; i++ {
+		reply.Push(reply.NewResult(nil))
+	}
+	return sc.err
+}
+
+func (sc *middlewareScope) Preview(result *scopes.Result, metadata *scopes.ActionMetadata, reply scopes.PreviewReplier, cancelled <-chan bool) error {
+	sc.calls = append(sc.calls, "Preview")
+	return sc.err
+}
+
+type activatorScope struct {
+	middlewareScope
+}
+
+func (sc *activatorScope) Activate(result *scopes.Result, metadata *scopes.ActionMetadata) (*scopes.ActivationResponse, error) {
+	sc.calls = append(sc.calls, "Activate")
+	return scopes.NewActivationResponse(scopes.ActivationHideDash), nil
+}
+
+// fakeSearchReply accepts every pushed result.
+type fakeSearchReply struct {
+	scopes.SearchReplier
+	pushed int
+}
+
+func (reply *fakeSearchReply) NewResult(category *scopes.Category) scopes.ResultSetter {
+	return scopes.NewTestingResult()
+}
+
+func (reply *fakeSearchReply) Push(result scopes.ResultSetter) error {
+	reply.pushed++
+	return nil
+}
+
+func recordingMiddleware(calls *[]string, name string) scopes.Middleware {
+	return scopes.Middleware{
+		Search: func(next scopes.SearchHandler) scopes.SearchHandler {
+			return func(query *scopes.CannedQuery, metadata *scopes.SearchMetadata, reply scopes.SearchReplier, cancelled <-chan bool) error {
+				*calls = append(*calls, "before "+name)
+				err := next(query, metadata, reply, cancelled)
+				*calls = append(*calls, "after "+name)
+				return err
+			}
+		},
+	}
+}
+
+func (s *S) TestChainOrder(c *C) {
+	inner := &middlewareScope{}
+	scope := scopes.Chain(inner, recordingMiddleware(&inner.calls, "a"), recordingMiddleware(&inner.calls, "b"))
+
+	query := scopes.NewCannedQuery("scope", "foo", "")
+	metadata := scopes.NewSearchMetadata(0, "us", "phone")
+	c.Check(scope.Search(query, metadata, &fakeSearchReply{}, nil), IsNil)
+	c.Check(inner.calls, DeepEquals, []string{"before a", "before b", "Search", "after b", "after a"})
+
+	scope.SetScopeBase(nil)
+	c.Check(inner.calls[len(inner.calls)-1], Equals, "SetScopeBase")
+}
+
+func (s *S) TestChainOptionalInterfaces(c *C) {
+	result := scopes.NewTestingResult()
+	metadata := scopes.NewActionMetadata("us", "phone")
+
+	// Scopes without Activator or PerformActioner are not handled
+	inner := &middlewareScope{}
+	scope := scopes.Chain(inner)
+	response, err := scope.(scopes.Activator).Activate(result, metadata)
+	c.Check(err, IsNil)
+	c.Check(response.Status, Equals, scopes.ActivationNotHandled)
+	response, err = scope.(scopes.PerformActioner).PerformAction(result, metadata, "widget", "action")
+	c.Check(err, IsNil)
+	c.Check(response.Status, Equals, scopes.ActivationNotHandled)
+	c.Check(scope.(scopes.SettingsDefiner).SettingsDefinition(), IsNil)
+
+	activator := &activatorScope{}
+	scope = scopes.Chain(activator)
+	response, err = scope.(scopes.Activator).Activate(result, metadata)
+	c.Check(err, IsNil)
+	c.Check(response.Status, Equals, scopes.ActivationHideDash)
+	c.Check(activator.calls, DeepEquals, []string{"Activate"})
+}
+
+func (s *S) TestLoggingMiddleware(c *C) {
+	var buf bytes.Buffer
+	inner := &middlewareScope{err: errors.New("search failed")}
+	scope := scopes.Chain(inner, scopes.LoggingMiddleware(log.New(&buf, "", 0)))
+
+	query := scopes.NewCannedQuery("scope", "foo", "")
+	metadata := scopes.NewSearchMetadata(0, "us", "phone")
+	c.Check(scope.Search(query, metadata, &fakeSearchReply{}, nil), ErrorMatches, "search failed")
+	c.Check(buf.String(), Matches, `Search "foo" started\nSearch "foo" finished in .* \(error: search failed\)\n`)
+}
+
+func (s *S) TestLatencyHistogram(c *C) {
+	h := scopes.NewLatencyHistogram(100*time.Millisecond, 10*time.Millisecond)
+	c.Check(h.Bounds(), DeepEquals, []time.Duration{10 * time.Millisecond, 100 * time.Millisecond})
+
+	h.Observe("Search", time.Millisecond)
+	h.Observe("Search", 50*time.Millisecond)
+	h.Observe("Search", time.Second)
+	h.Observe("Preview", 10*time.Millisecond)
+	c.Check(h.Counts("Search"), DeepEquals, []int64{1, 1, 1})
+	c.Check(h.Counts("Preview"), DeepEquals, []int64{1, 0, 0})
+	c.Check(h.Counts("Activate"), DeepEquals, []int64{0, 0, 0})
+
+	scope := scopes.Chain(&middlewareScope{}, h.Middleware())
+	query := scopes.NewCannedQuery("scope", "foo", "")
+	metadata := scopes.NewSearchMetadata(0, "us", "phone")
+	c.Check(scope.Search(query, metadata, &fakeSearchReply{}, nil), IsNil)
+	c.Check(h.Counts("Search"), DeepEquals, []int64{2, 1, 1})
+}
+
+func (s *S) TestResultCountMiddleware(c *C) {
+	var counted int
+	inner := &middlewareScope{results: 3}
+	scope := scopes.Chain(inner, scopes.ResultCountMiddleware(func(query *scopes.CannedQuery, count int) {
+		c.Check(query.QueryString(), Equals, "foo")
+		counted = count
+	}))
+
+	reply := &fakeSearchReply{}
+	query := scopes.NewCannedQuery("scope", "foo", "")
+	metadata := scopes.NewSearchMetadata(0, "us", "phone")
+	c.Check(scope.Search(query, metadata, reply, nil), IsNil)
+	c.Check(counted, Equals, 3)
+	c.Check(reply.pushed, Equals, 3)
+}
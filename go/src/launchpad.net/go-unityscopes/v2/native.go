@@ -0,0 +1,27 @@
+package scopes
+
+import (
+	"sync/atomic"
+)
+
+// liveObjects counts the objects owned by the scopes runtime that are
+// referenced from Go and have not been released yet.
+var liveObjects int64
+
+func trackObject() {
+	atomic.AddInt64(&liveObjects, 1)
+}
+
+func untrackObject() {
+	atomic.AddInt64(&liveObjects, -1)
+}
+
+// LiveNativeObjects returns the number of CannedQuery, Result,
+// CategorisedResult, Department, ColumnLayout, SearchMetadata and
+// ActionMetadata objects whose native counterparts have not been
+// released, either by calling Close or by the garbage collector.
+//
+// It is intended for tests that check for leaks.
+func LiveNativeObjects() int {
+	return int(atomic.LoadInt64(&liveObjects))
+}
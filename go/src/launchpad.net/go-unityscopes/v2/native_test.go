@@ -0,0 +1,77 @@
+// +build cgo,!purego
+
+package scopes_test
+
+import (
+	"io"
+	"runtime"
+	"time"
+
+	. "gopkg.in/check.v1"
+	"launchpad.net/go-unityscopes/v2"
+)
+
+// settleNativeObjects runs the garbage collector until objects left
+// over from earlier tests have been finalized, and returns the number
+// of live native objects.
+func settleNativeObjects() int {
+	live := scopes.LiveNativeObjects()
+	for i := 0; i < 10; i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+		now := scopes.LiveNativeObjects()
+		if now == live {
+			break
+		}
+		live = now
+	}
+	return live
+}
+
+func (s *S) TestCloseReleasesNativeObjects(c *C) {
+	before := settleNativeObjects()
+
+	query := scopes.NewCannedQuery("scope", "query", "")
+	dept, err := scopes.NewDepartment("dept", query, "Department")
+	c.Assert(err, IsNil)
+	closers := []io.Closer{
+		query,
+		dept,
+		scopes.NewTestingResult(),
+		scopes.NewCategorisedResult(scopes.NewTestingCategory("cat")),
+		scopes.NewColumnLayout(1),
+		scopes.NewSearchMetadata(0, "us", "phone"),
+		scopes.NewActionMetadata("us", "phone"),
+	}
+	c.Check(scopes.LiveNativeObjects(), Equals, before+len(closers))
+
+	for i, closer := range closers {
+		c.Check(closer.Close(), IsNil)
+		c.Check(scopes.LiveNativeObjects(), Equals, before+len(closers)-i-1)
+	}
+
+	// Closing again is harmless
+	for _, closer := range closers {
+		c.Check(closer.Close(), IsNil)
+	}
+	c.Check(scopes.LiveNativeObjects(), Equals, before)
+
+	// The finalizers do not release closed objects a second time
+	closers = nil
+	c.Check(settleNativeObjects(), Equals, before)
+}
+
+func (s *S) TestFinalizerReleasesNativeObjects(c *C) {
+	before := settleNativeObjects()
+	func() {
+		query := scopes.NewCannedQuery("scope", "query", "")
+		dept, err := scopes.NewDepartment("dept", query, "Department")
+		c.Assert(err, IsNil)
+		child, err := scopes.NewDepartment("child", query, "Child")
+		c.Assert(err, IsNil)
+		dept.AddSubdepartment(child)
+		c.Check(dept.Subdepartments(), HasLen, 1)
+		c.Check(scopes.LiveNativeObjects(), Equals, before+4)
+	}()
+	c.Check(settleNativeObjects(), Equals, before)
+}
@@ -0,0 +1,48 @@
+package scopes
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"runtime/debug"
+)
+
+var debugPanics = flag.Bool("debug-panics", false, "Re-panic after logging a panic in a scope callback instead of reporting it as an error")
+
+// PanicError is the error reported to the shell when a scope callback
+// panics.
+type PanicError struct {
+	// Callback is the name of the scope method that panicked.
+	Callback string
+	// Value is the value passed to panic.
+	Value interface{}
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+func (err *PanicError) Error() string {
+	return fmt.Sprintf("%s panicked: %v", err.Callback, err.Value)
+}
+
+// callRecovered calls f, turning a panic into a *PanicError.
+//
+// The panic is logged together with its stack trace.  If the scope
+// was started with --debug-panics, the panic is propagated after
+// logging so that the process crashes as it would without recovery.
+func callRecovered(callback string, f func() error) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			perr := &PanicError{
+				Callback: callback,
+				Value:    value,
+				Stack:    debug.Stack(),
+			}
+			log.Printf("%v\n%s", perr, perr.Stack)
+			if *debugPanics {
+				panic(value)
+			}
+			err = perr
+		}
+	}()
+	return f()
+}
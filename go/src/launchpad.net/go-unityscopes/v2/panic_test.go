@@ -0,0 +1,45 @@
+package scopes_test
+
+import (
+	"errors"
+
+	. "gopkg.in/check.v1"
+	"launchpad.net/go-unityscopes/v2"
+)
+
+func (s *S) TestCallRecoveredPassesThrough(c *C) {
+	err := scopes.CallRecovered("Search", func() error {
+		return nil
+	})
+	c.Check(err, IsNil)
+
+	expected := errors.New("search failed")
+	err = scopes.CallRecovered("Search", func() error {
+		return expected
+	})
+	c.Check(err, Equals, expected)
+}
+
+func (s *S) TestCallRecoveredPanic(c *C) {
+	err := scopes.CallRecovered("Preview", func() error {
+		var m map[string]int
+		m["foo"] = 1
+		return nil
+	})
+	c.Assert(err, FitsTypeOf, &scopes.PanicError{})
+	perr := err.(*scopes.PanicError)
+	c.Check(perr.Callback, Equals, "Preview")
+	c.Check(string(perr.Stack), Matches, "(?s).*TestCallRecoveredPanic.*")
+	c.Check(err, ErrorMatches, "Preview panicked: assignment to entry in nil map")
+}
+
+func (s *S) TestCallRecoveredDebugPanics(c *C) {
+	old := scopes.SetDebugPanics(true)
+	defer scopes.SetDebugPanics(old)
+
+	c.Check(func() {
+		scopes.CallRecovered("Activate", func() error {
+			panic("boom")
+		})
+	}, PanicMatches, "boom")
+}
@@ -1,3 +1,5 @@
+// +build cgo,!purego
+
 #include <stdexcept>
 #include <cstring>
 
@@ -1,3 +1,5 @@
+// +build cgo,!purego
+
 package scopes
 
 // #include <stdlib.h>
@@ -17,14 +19,26 @@ type CannedQuery struct {
 func finalizeCannedQuery(query *CannedQuery) {
 	if query.q != nil {
 		C.destroy_canned_query(query.q)
+		untrackObject()
 	}
 	query.q = nil
 }
 
+// Close releases the native query held by this CannedQuery.
+//
+// It is safe to call Close more than once, and the query must not
+// be used afterwards.  If Close is not called, the query is
+// released when the CannedQuery is garbage collected.
+func (query *CannedQuery) Close() error {
+	finalizeCannedQuery(query)
+	return nil
+}
+
 func makeCannedQuery(q *C._CannedQuery) *CannedQuery {
 	query := new(CannedQuery)
 	runtime.SetFinalizer(query, finalizeCannedQuery)
 	query.q = q
+	trackObject()
 	return query
 }
 
@@ -0,0 +1,122 @@
+package scopes
+
+// The query metadata types are defined separately for cgo builds, in
+// metadata.go, and builds without cgo, in metadata_nocgo.go.  This
+// file holds what they share.
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unsafe"
+)
+
+type ConnectivityStatus int
+
+const (
+	ConnectivityStatusUnknown      ConnectivityStatus = 0
+	ConnectivityStatusConnected    ConnectivityStatus = 1
+	ConnectivityStatusDisconnected ConnectivityStatus = 2
+)
+
+// Context returns a context that is cancelled when the shell cancels
+// the request.
+//
+// It is done at the same time as the cancelled channel passed to
+// Search or Preview is closed.  For metadata that is not attached to
+// a running request, the background context is returned.
+func (metadata *queryMetadata) Context() context.Context {
+	if metadata.ctx == nil {
+		return context.Background()
+	}
+	return metadata.ctx
+}
+
+// Deadline returns the time at which the request will be cancelled
+// because it has run for longer than the query timeout.  The ok
+// result is false if no timeout applies.
+func (metadata *queryMetadata) Deadline() (deadline time.Time, ok bool) {
+	return metadata.Context().Deadline()
+}
+
+// Remaining returns the time budget left before the request reaches
+// its deadline.  The ok result is false if no timeout applies.
+func (metadata *queryMetadata) Remaining() (remaining time.Duration, ok bool) {
+	deadline, ok := metadata.Deadline()
+	if !ok {
+		return 0, false
+	}
+	if remaining = time.Until(deadline); remaining < 0 {
+		remaining = 0
+	}
+	return remaining, true
+}
+
+type Location struct {
+	Latitude           float64 `json:"latitude"`
+	Longitude          float64 `json:"longitude"`
+	Altitude           float64 `json:"altitude"`
+	AreaCode           string  `json:"area_code"`
+	City               string  `json:"city"`
+	CountryCode        string  `json:"country_code"`
+	CountryName        string  `json:"country_name"`
+	HorizontalAccuracy float64 `json:"horizontal_accuracy"`
+	VerticalAccuracy   float64 `json:"vertical_accuracy"`
+	RegionCode         string  `json:"region_code"`
+	RegionName         string  `json:"region_name"`
+	ZipPostalCode      string  `json:"zip_postal_code"`
+}
+
+// we use this type to reimplement the marshaller interface in order to make values
+// like 1.0 not being converted as 1 (integer).
+type marshalFloat float64
+
+func (n marshalFloat) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("%f", n)), nil
+}
+
+// the following structure is only used to control how the float64 types are
+// marshaled. It is not exported.
+type locationMarshal struct {
+	Latitude           marshalFloat `json:"latitude"`
+	Longitude          marshalFloat `json:"longitude"`
+	Altitude           marshalFloat `json:"altitude"`
+	AreaCode           string       `json:"area_code"`
+	City               string       `json:"city"`
+	CountryCode        string       `json:"country_code"`
+	CountryName        string       `json:"country_name"`
+	HorizontalAccuracy marshalFloat `json:"horizontal_accuracy"`
+	VerticalAccuracy   marshalFloat `json:"vertical_accuracy"`
+	RegionCode         string       `json:"region_code"`
+	RegionName         string       `json:"region_name"`
+	ZipPostalCode      string       `json:"zip_postal_code"`
+}
+
+type ProxyScopeMetadata struct {
+	Identity string `json:"identity"`
+	EndPoint string `json:"endpoint"`
+}
+
+// ScopeMetadata holds scope attributes such as name, description, icon etc.
+//
+// The information stored by ScopeMetadata comes from the .ini file for the given scope (for local scopes)
+// or is fetched from the remote server (for scopes running on Smart Scopes Server).
+// Use ListRegistryScopes from ScopeBase to get the metadata for all scopes.
+type ScopeMetadata struct {
+	m                    unsafe.Pointer
+	Art                  string                 `json:"art"`
+	Author               string                 `json:"author"`
+	Description          string                 `json:"description"`
+	DisplayName          string                 `json:"display_name"`
+	Icon                 string                 `json:"icon"`
+	Invisible            bool                   `json:"invisible"`
+	IsAggregator         bool                   `json:"is_aggregator"`
+	LocationDataNeeded   bool                   `json:"location_data_needed"`
+	ScopeDir             string                 `json:"scope_dir"`
+	ScopeId              string                 `json:"scope_id"`
+	Version              int                    `json:"version"`
+	Proxy                ProxyScopeMetadata     `json:"proxy"`
+	AppearanceAttributes map[string]interface{} `json:"appearance_attributes"`
+	SettingsDefinitions  []interface{}          `json:"settings_definitions"`
+	Keywords             []string               `json:"keywords"`
+}
@@ -0,0 +1,101 @@
+// +build !cgo purego
+
+package scopes
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// CannedQuery represents a search query from the user.
+type CannedQuery struct {
+	scopeID      string
+	queryString  string
+	departmentID string
+	filterState  FilterState
+}
+
+// Close releases the native query held by this CannedQuery.
+//
+// Without cgo there is nothing to release, so Close does nothing.
+func (query *CannedQuery) Close() error {
+	return nil
+}
+
+// NewCannedQuery creates a new CannedQuery with the given scope ID,
+// query string and department ID.
+func NewCannedQuery(scopeID, queryString, departmentID string) *CannedQuery {
+	return &CannedQuery{
+		scopeID:      scopeID,
+		queryString:  queryString,
+		departmentID: departmentID,
+		filterState:  make(FilterState),
+	}
+}
+
+// ScopeID returns the scope ID for this canned query.
+func (query *CannedQuery) ScopeID() string {
+	return query.scopeID
+}
+
+// DepartmentID returns the department ID for this canned query.
+func (query *CannedQuery) DepartmentID() string {
+	return query.departmentID
+}
+
+// QueryString returns the query string for this canned query.
+func (query *CannedQuery) QueryString() string {
+	return query.queryString
+}
+
+// FilterState returns the state of the filters for this canned query.
+func (query *CannedQuery) FilterState() FilterState {
+	state := make(FilterState, len(query.filterState))
+	for id, value := range query.filterState {
+		state[id] = value
+	}
+	return state
+}
+
+// SetDepartmentID changes the department ID for this canned query.
+func (query *CannedQuery) SetDepartmentID(departmentID string) {
+	query.departmentID = departmentID
+}
+
+// SetQueryString changes the query string for this canned query.
+func (query *CannedQuery) SetQueryString(queryString string) {
+	query.queryString = queryString
+}
+
+// ToURI formats the canned query as a URI, in the same form as the
+// scopes runtime.
+func (query *CannedQuery) ToURI() string {
+	uri := "scope://" + url.QueryEscape(query.scopeID)
+	var params []string
+	if query.queryString != "" {
+		params = append(params, "q="+escapeQueryValue(query.queryString))
+	}
+	if query.departmentID != "" {
+		params = append(params, "dep="+escapeQueryValue(query.departmentID))
+	}
+	if len(params) > 0 {
+		uri += "?" + strings.Join(params, "&")
+	}
+	return uri
+}
+
+// escapeQueryValue percent encodes everything but letters and digits,
+// as the scopes runtime does.
+func escapeQueryValue(value string) string {
+	var buf []byte
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') {
+			buf = append(buf, c)
+		} else {
+			buf = append(buf, fmt.Sprintf("%%%02X", c)...)
+		}
+	}
+	return string(buf)
+}
@@ -0,0 +1,699 @@
+package scopes
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// RecordEnv is the environment variable that enables session
+// recording when the --record flag is not given.
+const RecordEnv = "GO_UNITYSCOPES_RECORD"
+
+var recordFile = flag.String("record", "", "Append a recording of every request and reply to the given JSON lines file")
+
+// Kinds of RecordEntry that start a new request.  All other kinds
+// record the scope's reply to the most recent request.
+const (
+	RecordSearch        = "search"
+	RecordPreview       = "preview"
+	RecordActivate      = "activate"
+	RecordPerformAction = "perform_action"
+)
+
+// RecordEntry is a single line of a session recording.
+//
+// Every incoming request (a search, preview, activation or action)
+// is given a new request number, and everything the scope sends in
+// reply is recorded with the same number.
+type RecordEntry struct {
+	Request int             `json:"request"`
+	Kind    string          `json:"kind"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// IsRequest reports whether the entry records an incoming request
+// rather than part of a reply.
+func (entry *RecordEntry) IsRequest() bool {
+	switch entry.Kind {
+	case RecordSearch, RecordPreview, RecordActivate, RecordPerformAction:
+		return true
+	}
+	return false
+}
+
+type recordedQuery struct {
+	ScopeID      string      `json:"scope_id"`
+	QueryString  string      `json:"query_string"`
+	DepartmentID string      `json:"department_id"`
+	FilterState  FilterState `json:"filter_state,omitempty"`
+}
+
+type recordedMetadata struct {
+	Locale      string `json:"locale"`
+	FormFactor  string `json:"form_factor"`
+	Cardinality int    `json:"cardinality,omitempty"`
+}
+
+type recordedSearch struct {
+	Query    recordedQuery    `json:"query"`
+	Metadata recordedMetadata `json:"metadata"`
+}
+
+type recordedAction struct {
+	Result   map[string]interface{} `json:"result"`
+	Metadata recordedMetadata       `json:"metadata"`
+	WidgetId string                 `json:"widget_id,omitempty"`
+	ActionId string                 `json:"action_id,omitempty"`
+}
+
+type recordedCategory struct {
+	Id       string `json:"id"`
+	Title    string `json:"title"`
+	Icon     string `json:"icon"`
+	Template string `json:"template"`
+}
+
+type recordedResult struct {
+	Category string                     `json:"category"`
+	Attrs    map[string]json.RawMessage `json:"attrs"`
+}
+
+type recordedFilters struct {
+	Filters []interface{} `json:"filters"`
+	State   FilterState   `json:"state"`
+}
+
+type recordedDepartment struct {
+	Id             string                `json:"id"`
+	Label          string                `json:"label"`
+	AlternateLabel string                `json:"alternate_label,omitempty"`
+	Subdepartments []*recordedDepartment `json:"subdepartments,omitempty"`
+}
+
+type recordedAttr struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+type recordedResponse struct {
+	Status    ActivationStatus `json:"status"`
+	Query     *recordedQuery   `json:"query,omitempty"`
+	ScopeData interface{}      `json:"scope_data,omitempty"`
+}
+
+type recordedError struct {
+	Message string `json:"message"`
+}
+
+func recordQuery(query *CannedQuery) *recordedQuery {
+	return &recordedQuery{
+		ScopeID:      query.ScopeID(),
+		QueryString:  query.QueryString(),
+		DepartmentID: query.DepartmentID(),
+		FilterState:  query.FilterState(),
+	}
+}
+
+func recordActionMetadata(metadata *ActionMetadata) recordedMetadata {
+	return recordedMetadata{
+		Locale:     metadata.Locale(),
+		FormFactor: metadata.FormFactor(),
+	}
+}
+
+func recordResultAttrs(result *Result) map[string]interface{} {
+	attrs, err := result.Attributes()
+	if err != nil {
+		log.Println("Could not record result:", err)
+	}
+	return attrs
+}
+
+func recordDepartment(dept *Department) *recordedDepartment {
+	recorded := &recordedDepartment{
+		Id:             dept.Id(),
+		Label:          dept.Label(),
+		AlternateLabel: dept.AlternateLabel(),
+	}
+	for _, child := range dept.Subdepartments() {
+		recorded.Subdepartments = append(recorded.Subdepartments, recordDepartment(child))
+	}
+	return recorded
+}
+
+// Recorder writes a recording of the requests a scope receives and
+// the replies it sends as JSON lines.
+//
+// A Recorder is enabled for a running scope with the --record flag or
+// the GO_UNITYSCOPES_RECORD environment variable, and recordings can
+// be played back against a scope with Replay.
+type Recorder struct {
+	lock        sync.Mutex
+	w           io.Writer
+	lastRequest int
+}
+
+// NewRecorder creates a Recorder writing to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{w: w}
+}
+
+func (r *Recorder) newRequest() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.lastRequest++
+	return r.lastRequest
+}
+
+func (r *Recorder) record(request int, kind string, data interface{}) {
+	entry := RecordEntry{
+		Request: request,
+		Kind:    kind,
+	}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			log.Printf("Could not record %s: %v", kind, err)
+			return
+		}
+		entry.Data = raw
+	}
+	line, err := json.Marshal(&entry)
+	if err != nil {
+		log.Printf("Could not record %s: %v", kind, err)
+		return
+	}
+	line = append(line, '\n')
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, err := r.w.Write(line); err != nil {
+		log.Println("Could not write recording:", err)
+	}
+}
+
+func (r *Recorder) recordResponse(request int, response *ActivationResponse, err error) {
+	if err != nil {
+		r.record(request, "error", recordedError{err.Error()})
+		return
+	}
+	if response == nil {
+		return
+	}
+	recorded := recordedResponse{
+		Status:    response.Status,
+		ScopeData: response.ScopeData,
+	}
+	if response.Query != nil {
+		recorded.Query = recordQuery(response.Query)
+	}
+	r.record(request, "response", recorded)
+}
+
+// Middleware returns middleware that records every request passing
+// through it, along with the scope's replies.
+func (r *Recorder) Middleware() Middleware {
+	return Middleware{
+		Search: func(next SearchHandler) SearchHandler {
+			return func(query *CannedQuery, metadata *SearchMetadata, reply SearchReplier, cancelled <-chan bool) error {
+				request := r.newRequest()
+				r.record(request, RecordSearch, recordedSearch{
+					Query: *recordQuery(query),
+					Metadata: recordedMetadata{
+						Locale:      metadata.Locale(),
+						FormFactor:  metadata.FormFactor(),
+						Cardinality: metadata.Cardinality(),
+					},
+				})
+				recording := &recordingSearchReply{
+					SearchReplier: reply,
+					recorder:      r,
+					request:       request,
+				}
+				err := next(query, metadata, recording, cancelled)
+				recording.finish(err)
+				return err
+			}
+		},
+		Preview: func(next PreviewHandler) PreviewHandler {
+			return func(result *Result, metadata *ActionMetadata, reply PreviewReplier, cancelled <-chan bool) error {
+				request := r.newRequest()
+				r.record(request, RecordPreview, recordedAction{
+					Result:   recordResultAttrs(result),
+					Metadata: recordActionMetadata(metadata),
+				})
+				recording := &recordingPreviewReply{
+					PreviewReplier: reply,
+					recorder:       r,
+					request:        request,
+				}
+				err := next(result, metadata, recording, cancelled)
+				recording.finish(err)
+				return err
+			}
+		},
+		Activate: func(next ActivateHandler) ActivateHandler {
+			return func(result *Result, metadata *ActionMetadata) (*ActivationResponse, error) {
+				request := r.newRequest()
+				r.record(request, RecordActivate, recordedAction{
+					Result:   recordResultAttrs(result),
+					Metadata: recordActionMetadata(metadata),
+				})
+				response, err := next(result, metadata)
+				r.recordResponse(request, response, err)
+				return response, err
+			}
+		},
+		PerformAction: func(next PerformActionHandler) PerformActionHandler {
+			return func(result *Result, metadata *ActionMetadata, widgetId, actionId string) (*ActivationResponse, error) {
+				request := r.newRequest()
+				r.record(request, RecordPerformAction, recordedAction{
+					Result:   recordResultAttrs(result),
+					Metadata: recordActionMetadata(metadata),
+					WidgetId: widgetId,
+					ActionId: actionId,
+				})
+				response, err := next(result, metadata, widgetId, actionId)
+				r.recordResponse(request, response, err)
+				return response, err
+			}
+		},
+	}
+}
+
+// recordingResult captures the attributes set on a result so they
+// can be recorded or cached when it is pushed.
+type recordingResult struct {
+	ResultSetter
+	category            string
+	attrs               map[string]json.RawMessage
+	interceptActivation bool
+}
+
+func newRecordingResult(result ResultSetter, category *Category) *recordingResult {
+	return &recordingResult{
+		ResultSetter: result,
+		category:     category.Id(),
+		attrs:        make(map[string]json.RawMessage),
+	}
+}
+
+func (result *recordingResult) Set(attr string, value interface{}) error {
+	if err := result.ResultSetter.Set(attr, value); err != nil {
+		return err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	result.attrs[attr] = data
+	return nil
+}
+
+func (result *recordingResult) SetURI(uri string) error {
+	return result.Set("uri", uri)
+}
+
+func (result *recordingResult) SetTitle(title string) error {
+	return result.Set("title", title)
+}
+
+func (result *recordingResult) SetArt(art string) error {
+	return result.Set("art", art)
+}
+
+func (result *recordingResult) SetDndURI(uri string) error {
+	return result.Set("dnd_uri", uri)
+}
+
+func (result *recordingResult) SetInterceptActivation() {
+	result.ResultSetter.SetInterceptActivation()
+	result.interceptActivation = true
+}
+
+type recordingSearchReply struct {
+	SearchReplier
+	recorder *Recorder
+	request  int
+	once     sync.Once
+}
+
+func (reply *recordingSearchReply) finish(err error) {
+	reply.once.Do(func() {
+		if err != nil {
+			reply.recorder.record(reply.request, "error", recordedError{err.Error()})
+		} else {
+			reply.recorder.record(reply.request, "finished", nil)
+		}
+	})
+}
+
+func (reply *recordingSearchReply) Finished() {
+	reply.finish(nil)
+	reply.SearchReplier.Finished()
+}
+
+func (reply *recordingSearchReply) Error(err error) {
+	reply.finish(err)
+	reply.SearchReplier.Error(err)
+}
+
+func (reply *recordingSearchReply) RegisterCategory(id, title, icon, template string) *Category {
+	reply.recorder.record(reply.request, "category", recordedCategory{id, title, icon, template})
+	return reply.SearchReplier.RegisterCategory(id, title, icon, template)
+}
+
+func (reply *recordingSearchReply) RegisterDepartments(parent *Department) {
+	reply.recorder.record(reply.request, "departments", recordDepartment(parent))
+	reply.SearchReplier.RegisterDepartments(parent)
+}
+
+func (reply *recordingSearchReply) NewResult(category *Category) ResultSetter {
+	return newRecordingResult(reply.SearchReplier.NewResult(category), category)
+}
+
+// unwrapResult returns the result to pass on to the wrapped reply,
+// along with the attributes to record for it.
+func unwrapResult(result ResultSetter) (ResultSetter, recordedResult) {
+	recorded := recordedResult{}
+	switch res := result.(type) {
+	case *recordingResult:
+		result = res.ResultSetter
+		recorded.Category = res.category
+		recorded.Attrs = res.attrs
+	case *ResultBuilder:
+		recorded.Category = res.category.Id()
+		recorded.Attrs = res.attrs
+	case *CategorisedResult:
+		recorded.Attrs = make(map[string]json.RawMessage)
+		for name, value := range recordResultAttrs(&res.Result) {
+			data, _ := json.Marshal(value)
+			recorded.Attrs[name] = data
+		}
+	}
+	return result, recorded
+}
+
+func (reply *recordingSearchReply) Push(result ResultSetter) error {
+	result, recorded := unwrapResult(result)
+	if err := reply.SearchReplier.Push(result); err != nil {
+		return err
+	}
+	reply.recorder.record(reply.request, "result", recorded)
+	return nil
+}
+
+func (reply *recordingSearchReply) PushAll(results ...ResultSetter) error {
+	unwrapped := make([]ResultSetter, len(results))
+	recorded := make([]recordedResult, len(results))
+	for i, result := range results {
+		unwrapped[i], recorded[i] = unwrapResult(result)
+	}
+	if err := PushResults(reply.SearchReplier, unwrapped...); err != nil {
+		return err
+	}
+	for _, res := range recorded {
+		reply.recorder.record(reply.request, "result", res)
+	}
+	return nil
+}
+
+func (reply *recordingSearchReply) PushFilters(filters []Filter, state FilterState) error {
+	if err := reply.SearchReplier.PushFilters(filters, state); err != nil {
+		return err
+	}
+	filterData := make([]interface{}, len(filters))
+	for i, f := range filters {
+		filterData[i] = f.serializeFilter()
+	}
+	reply.recorder.record(reply.request, "filters", recordedFilters{filterData, state})
+	return nil
+}
+
+type recordingPreviewReply struct {
+	PreviewReplier
+	recorder *Recorder
+	request  int
+	once     sync.Once
+}
+
+func (reply *recordingPreviewReply) finish(err error) {
+	reply.once.Do(func() {
+		if err != nil {
+			reply.recorder.record(reply.request, "error", recordedError{err.Error()})
+		} else {
+			reply.recorder.record(reply.request, "finished", nil)
+		}
+	})
+}
+
+func (reply *recordingPreviewReply) Finished() {
+	reply.finish(nil)
+	reply.PreviewReplier.Finished()
+}
+
+func (reply *recordingPreviewReply) Error(err error) {
+	reply.finish(err)
+	reply.PreviewReplier.Error(err)
+}
+
+func (reply *recordingPreviewReply) PushWidgets(widgets ...PreviewWidget) error {
+	if err := reply.PreviewReplier.PushWidgets(widgets...); err != nil {
+		return err
+	}
+	reply.recorder.record(reply.request, "widgets", widgets)
+	return nil
+}
+
+func (reply *recordingPreviewReply) PushAttr(attr string, value interface{}) error {
+	if err := reply.PreviewReplier.PushAttr(attr, value); err != nil {
+		return err
+	}
+	reply.recorder.record(reply.request, "attr", recordedAttr{attr, value})
+	return nil
+}
+
+func (reply *recordingPreviewReply) RegisterLayout(layout ...*ColumnLayout) error {
+	if err := reply.PreviewReplier.RegisterLayout(layout...); err != nil {
+		return err
+	}
+	layouts := make([][][]string, len(layout))
+	for i, l := range layout {
+		for column := 0; column < l.Size(); column++ {
+			widgets, _ := l.Column(column)
+			layouts[i] = append(layouts[i], widgets)
+		}
+	}
+	reply.recorder.record(reply.request, "layouts", layouts)
+	return nil
+}
+
+// recordingScope wraps scope in a Recorder if recording was
+// requested on the command line or in the environment.  The returned
+// function closes the recording.
+func recordingScope(scope Scope) (Scope, func(), error) {
+	path := *recordFile
+	if path == "" {
+		path = os.Getenv(RecordEnv)
+	}
+	if path == "" {
+		return scope, func() {}, nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Println("Recording session to", path)
+	return Chain(scope, NewRecorder(f).Middleware()), func() { f.Close() }, nil
+}
+
+// ReadRecording reads the entries of a session recording.
+func ReadRecording(r io.Reader) ([]RecordEntry, error) {
+	var entries []RecordEntry
+	decoder := json.NewDecoder(r)
+	for {
+		var entry RecordEntry
+		if err := decoder.Decode(&entry); err == io.EOF {
+			return entries, nil
+		} else if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// discardResult is the ResultSetter used by replay replies.
+type discardResult struct{}
+
+func (discardResult) Set(attr string, value interface{}) error { return nil }
+func (discardResult) SetURI(uri string) error                  { return nil }
+func (discardResult) SetTitle(title string) error              { return nil }
+func (discardResult) SetArt(art string) error                  { return nil }
+func (discardResult) SetDndURI(uri string) error               { return nil }
+func (discardResult) SetInterceptActivation()                  {}
+
+// replaySearchReply accepts everything pushed to it.  The recorder
+// wrapping it captures the reply.
+type replaySearchReply struct{}
+
+func (replaySearchReply) Finished()                              {}
+func (replaySearchReply) Error(err error)                        {}
+func (replaySearchReply) RegisterDepartments(parent *Department) {}
+func (replaySearchReply) RegisterCategory(id, title, icon, template string) *Category {
+	return NewCategory(id, title, icon, template)
+}
+func (replaySearchReply) NewResult(category *Category) ResultSetter             { return discardResult{} }
+func (replaySearchReply) Push(result ResultSetter) error                        { return nil }
+func (replaySearchReply) PushFilters(filters []Filter, state FilterState) error { return nil }
+
+type replayPreviewReply struct{}
+
+func (replayPreviewReply) Finished()                                     {}
+func (replayPreviewReply) Error(err error)                               {}
+func (replayPreviewReply) PushWidgets(widgets ...PreviewWidget) error    { return nil }
+func (replayPreviewReply) PushAttr(attr string, value interface{}) error { return nil }
+func (replayPreviewReply) RegisterLayout(layout ...*ColumnLayout) error  { return nil }
+
+func replayResult(attrs map[string]interface{}) (*Result, error) {
+	result := newTestingResult()
+	for name, value := range attrs {
+		if err := result.Set(name, value); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// Replay runs the requests in a recording against scope, and returns
+// a new recording of the scope's replies.
+//
+// Requests keep their original numbers, so the result can be compared
+// with the original recording using DiffRecordings.  Filter states
+// are recorded but can not be restored into replayed queries.
+func Replay(scope Scope, entries []RecordEntry) ([]RecordEntry, error) {
+	var buf bytes.Buffer
+	recorder := NewRecorder(&buf)
+	replayed := Chain(scope, recorder.Middleware())
+	for _, entry := range entries {
+		if !entry.IsRequest() {
+			continue
+		}
+		recorder.lastRequest = entry.Request - 1
+
+		var err error
+		switch entry.Kind {
+		case RecordSearch:
+			var search recordedSearch
+			if err = json.Unmarshal(entry.Data, &search); err != nil {
+				break
+			}
+			query := NewCannedQuery(search.Query.ScopeID, search.Query.QueryString, search.Query.DepartmentID)
+			metadata := NewSearchMetadata(search.Metadata.Cardinality, search.Metadata.Locale, search.Metadata.FormFactor)
+			callRecovered("Search", func() error {
+				return replayed.Search(query, metadata, replaySearchReply{}, make(chan bool))
+			})
+		default:
+			var action recordedAction
+			if err = json.Unmarshal(entry.Data, &action); err != nil {
+				break
+			}
+			var result *Result
+			if result, err = replayResult(action.Result); err != nil {
+				break
+			}
+			metadata := NewActionMetadata(action.Metadata.Locale, action.Metadata.FormFactor)
+			switch entry.Kind {
+			case RecordPreview:
+				callRecovered("Preview", func() error {
+					return replayed.Preview(result, metadata, replayPreviewReply{}, make(chan bool))
+				})
+			case RecordActivate:
+				callRecovered("Activate", func() error {
+					_, err := replayed.(Activator).Activate(result, metadata)
+					return err
+				})
+			case RecordPerformAction:
+				callRecovered("PerformAction", func() error {
+					_, err := replayed.(PerformActioner).PerformAction(result, metadata, action.WidgetId, action.ActionId)
+					return err
+				})
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Could not replay request %d: %v", entry.Request, err)
+		}
+	}
+	return ReadRecording(&buf)
+}
+
+func groupRecording(entries []RecordEntry) (map[int][]RecordEntry, []int) {
+	groups := make(map[int][]RecordEntry)
+	var requests []int
+	for _, entry := range entries {
+		if _, ok := groups[entry.Request]; !ok {
+			requests = append(requests, entry.Request)
+			groups[entry.Request] = nil
+		}
+		if !entry.IsRequest() {
+			groups[entry.Request] = append(groups[entry.Request], entry)
+		}
+	}
+	return groups, requests
+}
+
+func sameEntry(a, b RecordEntry) bool {
+	if a.Kind != b.Kind {
+		return false
+	}
+	var aData, bData interface{}
+	json.Unmarshal(a.Data, &aData)
+	json.Unmarshal(b.Data, &bData)
+	return reflect.DeepEqual(aData, bData)
+}
+
+func describeEntry(entry RecordEntry) string {
+	if len(entry.Data) == 0 {
+		return entry.Kind
+	}
+	return entry.Kind + " " + string(entry.Data)
+}
+
+// DiffRecordings compares the replies in two recordings request by
+// request, and returns a description of each reply entry that
+// differs.  Data is compared as decoded JSON, so formatting and key
+// order are ignored.
+func DiffRecordings(expected, actual []RecordEntry) []string {
+	expectedGroups, requests := groupRecording(expected)
+	actualGroups, actualRequests := groupRecording(actual)
+	for _, request := range actualRequests {
+		if _, ok := expectedGroups[request]; !ok {
+			requests = append(requests, request)
+		}
+	}
+	sort.Ints(requests)
+
+	var diffs []string
+	for _, request := range requests {
+		exp := expectedGroups[request]
+		act := actualGroups[request]
+		for i := 0; i < len(exp) || i < len(act); i++ {
+			switch {
+			case i >= len(exp):
+				diffs = append(diffs, fmt.Sprintf("request %d: unexpected %s", request, describeEntry(act[i])))
+			case i >= len(act):
+				diffs = append(diffs, fmt.Sprintf("request %d: missing %s", request, describeEntry(exp[i])))
+			case !sameEntry(exp[i], act[i]):
+				diffs = append(diffs, fmt.Sprintf("request %d: expected %s, got %s", request, describeEntry(exp[i]), describeEntry(act[i])))
+			}
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,167 @@
+package scopes_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	. "gopkg.in/check.v1"
+	"launchpad.net/go-unityscopes/v2"
+)
+
+type recordedScope struct {
+	title string
+}
+
+func (sc *recordedScope) SetScopeBase(base *scopes.ScopeBase) {}
+
+func (sc *recordedScope) Search(query *scopes.CannedQuery, metadata *scopes.SearchMetadata, reply scopes.SearchReplier, cancelled <-chan bool) error {
+	if query.QueryString() == "fail" {
+		return errors.New("search failed")
+	}
+	cat := reply.RegisterCategory("cat", "Category", "", "")
+	result := reply.NewResult(cat)
+	result.SetURI("http://example.com")
+	result.SetTitle(sc.title)
+	return reply.Push(result)
+}
+
+func (sc *recordedScope) Preview(result *scopes.Result, metadata *scopes.ActionMetadata, reply scopes.PreviewReplier, cancelled <-chan bool) error {
+	widget := scopes.NewPreviewWidget("header", "header")
+	widget.AddAttributeValue("title", sc.title)
+	widget.AddAttributeValue("subtitle", result.URI())
+	return reply.PushWidgets(widget)
+}
+
+// testSearchReply records the categories and result titles pushed to it.
+type testSearchReply struct {
+	scopes.SearchReplier
+	categories []string
+	titles     []string
+}
+
+func (reply *testSearchReply) RegisterCategory(id, title, icon, template string) *scopes.Category {
+	reply.categories = append(reply.categories, id)
+	return scopes.NewCategory(id, title, icon, template)
+}
+
+func (reply *testSearchReply) NewResult(category *scopes.Category) scopes.ResultSetter {
+	return scopes.NewTestingResult()
+}
+
+func (reply *testSearchReply) Push(result scopes.ResultSetter) error {
+	reply.titles = append(reply.titles, result.(*scopes.Result).Title())
+	return nil
+}
+
+type recordingPreviewReply struct {
+	scopes.PreviewReplier
+}
+
+func (reply *recordingPreviewReply) PushWidgets(widgets ...scopes.PreviewWidget) error {
+	return nil
+}
+
+func (s *S) TestRecorder(c *C) {
+	var buf bytes.Buffer
+	scope := scopes.Chain(&recordedScope{title: "Title"}, scopes.NewRecorder(&buf).Middleware())
+
+	query := scopes.NewCannedQuery("scope", "foo", "dept")
+	metadata := scopes.NewSearchMetadata(10, "us", "phone")
+	c.Check(scope.Search(query, metadata, &testSearchReply{}, nil), IsNil)
+	c.Check(scope.Search(scopes.NewCannedQuery("scope", "fail", ""), metadata, &testSearchReply{}, nil), ErrorMatches, "search failed")
+
+	result := scopes.NewTestingResult()
+	c.Check(result.SetURI("http://example.com"), IsNil)
+	c.Check(result.SetTitle("Title"), IsNil)
+	c.Check(scope.Preview(result, scopes.NewActionMetadata("us", "phone"), &recordingPreviewReply{}, nil), IsNil)
+
+	entries, err := scopes.ReadRecording(&buf)
+	c.Assert(err, IsNil)
+	var kinds []string
+	for _, entry := range entries {
+		kinds = append(kinds, entry.Kind)
+	}
+	c.Check(kinds, DeepEquals, []string{
+		"search", "category", "result", "finished",
+		"search", "error",
+		"preview", "widgets", "finished",
+	})
+	c.Check(entries[0].Request, Equals, 1)
+	c.Check(entries[0].IsRequest(), Equals, true)
+	c.Check(entries[1].IsRequest(), Equals, false)
+	c.Check(entries[4].Request, Equals, 2)
+	c.Check(entries[6].Request, Equals, 3)
+
+	var search map[string]interface{}
+	c.Assert(json.Unmarshal(entries[0].Data, &search), IsNil)
+	c.Check(search["query"], DeepEquals, map[string]interface{}{
+		"scope_id":      "scope",
+		"query_string":  "foo",
+		"department_id": "dept",
+	})
+	c.Check(search["metadata"], DeepEquals, map[string]interface{}{
+		"locale":      "us",
+		"form_factor": "phone",
+		"cardinality": float64(10),
+	})
+
+	var res map[string]interface{}
+	c.Assert(json.Unmarshal(entries[2].Data, &res), IsNil)
+	c.Check(res, DeepEquals, map[string]interface{}{
+		"category": "cat",
+		"attrs": map[string]interface{}{
+			"uri":   "http://example.com",
+			"title": "Title",
+		},
+	})
+	c.Check(string(entries[5].Data), Equals, `{"message":"search failed"}`)
+}
+
+func (s *S) TestReplay(c *C) {
+	var buf bytes.Buffer
+	scope := scopes.Chain(&recordedScope{title: "Title"}, scopes.NewRecorder(&buf).Middleware())
+	metadata := scopes.NewSearchMetadata(10, "us", "phone")
+	c.Check(scope.Search(scopes.NewCannedQuery("scope", "foo", ""), metadata, &testSearchReply{}, nil), IsNil)
+	c.Check(scope.Search(scopes.NewCannedQuery("scope", "fail", ""), metadata, &testSearchReply{}, nil), NotNil)
+	result := scopes.NewTestingResult()
+	c.Check(result.SetURI("http://example.com"), IsNil)
+	c.Check(result.SetTitle("Title"), IsNil)
+	c.Check(scope.Preview(result, scopes.NewActionMetadata("us", "phone"), &recordingPreviewReply{}, nil), IsNil)
+
+	recording, err := scopes.ReadRecording(&buf)
+	c.Assert(err, IsNil)
+
+	// Replaying against the same scope gives the same replies
+	replayed, err := scopes.Replay(&recordedScope{title: "Title"}, recording)
+	c.Assert(err, IsNil)
+	c.Check(scopes.DiffRecordings(recording, replayed), HasLen, 0)
+
+	// Changes in the scope's output are reported
+	replayed, err = scopes.Replay(&recordedScope{title: "Changed"}, recording)
+	c.Assert(err, IsNil)
+	diffs := scopes.DiffRecordings(recording, replayed)
+	c.Assert(diffs, HasLen, 2)
+	c.Check(diffs[0], Matches, `request 1: expected result .*"Title".*, got result .*"Changed".*`)
+	c.Check(diffs[1], Matches, `request 3: expected widgets .*"Title".*, got widgets .*"Changed".*`)
+}
+
+func (s *S) TestDiffRecordings(c *C) {
+	expected := []scopes.RecordEntry{
+		{Request: 1, Kind: "search"},
+		{Request: 1, Kind: "category", Data: json.RawMessage(`{"id": "a", "title": "A"}`)},
+		{Request: 1, Kind: "finished"},
+	}
+	actual := []scopes.RecordEntry{
+		{Request: 1, Kind: "search"},
+		{Request: 1, Kind: "category", Data: json.RawMessage(`{"title":"A","id":"a"}`)},
+		{Request: 1, Kind: "finished"},
+	}
+	c.Check(scopes.DiffRecordings(expected, actual), HasLen, 0)
+
+	actual = append(actual[:2], scopes.RecordEntry{Request: 2, Kind: "finished"})
+	c.Check(scopes.DiffRecordings(expected, actual), DeepEquals, []string{
+		"request 1: missing finished",
+		"request 2: unexpected finished",
+	})
+}
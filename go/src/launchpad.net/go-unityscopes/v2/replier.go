@@ -0,0 +1,46 @@
+package scopes
+
+// ResultSetter is implemented by results whose attributes can be
+// set.  Both Result and CategorisedResult implement it.
+type ResultSetter interface {
+	Set(attr string, value interface{}) error
+	SetURI(uri string) error
+	SetTitle(title string) error
+	SetArt(art string) error
+	SetDndURI(uri string) error
+	SetInterceptActivation()
+}
+
+// SearchReplier is the interface through which a scope sends search
+// results to the client.
+//
+// SearchReply implements it for the scopes runtime.  Scopes should
+// create their results with NewResult rather than
+// NewCategorisedResult, so that alternative implementations can
+// supply their own result type.
+type SearchReplier interface {
+	Finished()
+	Error(err error)
+	RegisterCategory(id, title, icon, template string) *Category
+	RegisterDepartments(parent *Department)
+	NewResult(category *Category) ResultSetter
+	Push(result ResultSetter) error
+	PushFilters(filters []Filter, state FilterState) error
+}
+
+// PreviewReplier is the interface through which a scope sends result
+// previews to the client.
+//
+// PreviewReply implements it for the scopes runtime.
+type PreviewReplier interface {
+	Finished()
+	Error(err error)
+	PushWidgets(widgets ...PreviewWidget) error
+	PushAttr(attr string, value interface{}) error
+	RegisterLayout(layout ...*ColumnLayout) error
+}
+
+var (
+	_ ResultSetter = (*Result)(nil)
+	_ ResultSetter = (*CategorisedResult)(nil)
+)
@@ -0,0 +1,34 @@
+package scopes_test
+
+import (
+	. "gopkg.in/check.v1"
+	"launchpad.net/go-unityscopes/v2"
+)
+
+func (s *S) TestNewCategory(c *C) {
+	cat := scopes.NewCategory("apps", "Apps", "icon.png", `{"schema-version": 1}`)
+	c.Check(cat.Id(), Equals, "apps")
+	c.Check(cat.Title(), Equals, "Apps")
+	c.Check(cat.Icon(), Equals, "icon.png")
+	c.Check(cat.Template(), Equals, `{"schema-version": 1}`)
+
+	// Only categories registered with a SearchReply can back a
+	// CategorisedResult.
+	c.Check(func() { scopes.NewCategorisedResult(cat) }, PanicMatches, "Category apps is not registered with a SearchReply")
+}
+
+func (s *S) TestResultSetter(c *C) {
+	var setter scopes.ResultSetter = scopes.NewTestingResult()
+	c.Check(setter.SetURI("http://example.com"), IsNil)
+	c.Check(setter.SetTitle("The title"), IsNil)
+	c.Check(setter.Set("subtitle", "The subtitle"), IsNil)
+	setter.SetInterceptActivation()
+
+	r := setter.(*scopes.Result)
+	c.Check(r.URI(), Equals, "http://example.com")
+	c.Check(r.Title(), Equals, "The title")
+
+	var subtitle string
+	c.Check(r.Get("subtitle", &subtitle), IsNil)
+	c.Check(subtitle, Equals, "The subtitle")
+}
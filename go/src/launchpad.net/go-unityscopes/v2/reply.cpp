@@ -1,3 +1,5 @@
+// +build cgo,!purego
+
 #include <stdexcept>
 #include <cstring>
 #include <iostream>
@@ -47,12 +49,30 @@ void search_reply_register_departments(SharedPtrData reply, SharedPtrData dept)
     get_ptr<SearchReply>(reply)->register_departments(get_ptr<Department>(dept));
 }
 
-void search_reply_push(SharedPtrData reply, _CategorisedResult *result, char **error) {
+// Returns 0 if the query has been cancelled or finished.
+int search_reply_push(SharedPtrData reply, _CategorisedResult *result, char **error) {
     try {
-        get_ptr<SearchReply>(reply)->push(*reinterpret_cast<CategorisedResult*>(result));
+        return get_ptr<SearchReply>(reply)->push(*reinterpret_cast<CategorisedResult*>(result));
+    } catch (const std::exception &e) {
+        *error = exception_string(e);
+    }
+    return 0;
+}
+
+// Returns 0 if the query has been cancelled or finished.
+int search_reply_push_all(SharedPtrData reply, SharedPtrData *categories, void *results_json, int count, char **error) {
+    try {
+        auto r = get_ptr<SearchReply>(reply);
+        for (const auto &result : build_results(categories, results_json, count)) {
+            if (!r->push(result)) {
+                return 0;
+            }
+        }
+        return 1;
     } catch (const std::exception &e) {
-        *error = strdup(e.what());
+        *error = exception_string(e);
     }
+    return 0;
 }
 
 void search_reply_push_filters(SharedPtrData reply, void *filters_json, void *filter_state_json, char **error) {
@@ -71,7 +91,7 @@ void search_reply_push_filters(SharedPtrData reply, void *filters_json, void *fi
         auto filter_state = FilterState::deserialize(filter_state_var.get_dict());
         get_ptr<SearchReply>(reply)->push(filters, filter_state);
     } catch (const std::exception &e) {
-        *error = strdup(e.what());
+        *error = exception_string(e);
     }
 #endif
 }
@@ -104,7 +124,7 @@ void preview_reply_push_widgets(SharedPtrData reply, void *gostring_array, int c
         }
         get_ptr<PreviewReply>(reply)->push(widgets);
     } catch (const std::exception &e) {
-        *error = strdup(e.what());
+        *error = exception_string(e);
     }
 }
 
@@ -113,7 +133,7 @@ void preview_reply_push_attr(SharedPtrData reply, void *key, void *json_value, c
         Variant value = Variant::deserialize_json(from_gostring(json_value));
         get_ptr<PreviewReply>(reply)->push(from_gostring(key), value);
     } catch (const std::exception &e) {
-        *error = strdup(e.what());
+        *error = exception_string(e);
     }
 }
 
@@ -126,6 +146,6 @@ void preview_reply_register_layout(SharedPtrData reply, _ColumnLayout **layout,
         }
         get_ptr<PreviewReply>(reply)->register_layout(api_layout_list);
     } catch (const std::exception &e) {
-        *error = strdup(e.what());
+        *error = exception_string(e);
     }
 }
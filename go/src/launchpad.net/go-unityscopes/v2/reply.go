@@ -1,3 +1,5 @@
+// +build cgo,!purego
+
 package scopes
 
 // #include <stdlib.h>
@@ -5,13 +7,24 @@ package scopes
 import "C"
 import (
 	"encoding/json"
+	"fmt"
 	"runtime"
+	"sync"
+	"sync/atomic"
 	"unsafe"
 )
 
+var (
+	_ SearchReplier  = (*SearchReply)(nil)
+	_ PreviewReplier = (*PreviewReply)(nil)
+)
+
 // SearchReply is used to send results of search queries to the client.
 type SearchReply struct {
 	r C.SharedPtrData
+
+	finish sync.Once
+	closed int32
 }
 
 func makeSearchReply(replyData *C.uintptr_t) *SearchReply {
@@ -29,9 +42,13 @@ func finalizeSearchReply(reply *SearchReply) {
 // pushed to this reply.
 //
 // This is called automatically if a scope's Search method completes
-// without error.
+// without error.  Only the first call to Finished or Error has any
+// effect.
 func (reply *SearchReply) Finished() {
-	C.search_reply_finished(&reply.r[0])
+	reply.finish.Do(func() {
+		atomic.StoreInt32(&reply.closed, 1)
+		C.search_reply_finished(&reply.r[0])
+	})
 }
 
 // Error is called to indicate that search query could not be
@@ -40,8 +57,11 @@ func (reply *SearchReply) Finished() {
 // This is called automatically if a scope's Search method completes
 // with an error.
 func (reply *SearchReply) Error(err error) {
-	errString := err.Error()
-	C.search_reply_error(&reply.r[0], unsafe.Pointer(&errString))
+	reply.finish.Do(func() {
+		atomic.StoreInt32(&reply.closed, 1)
+		errString := err.Error()
+		C.search_reply_error(&reply.r[0], unsafe.Pointer(&errString))
+	})
 }
 
 // RegisterCategory registers a new results category with the client.
@@ -54,7 +74,7 @@ func (reply *SearchReply) Error(err error) {
 // Categories can be passed to NewCategorisedResult in order to
 // construct search results.
 func (reply *SearchReply) RegisterCategory(id, title, icon, template string) *Category {
-	cat := new(Category)
+	cat := NewCategory(id, title, icon, template)
 	runtime.SetFinalizer(cat, finalizeCategory)
 	C.search_reply_register_category(&reply.r[0], unsafe.Pointer(&id), unsafe.Pointer(&title), unsafe.Pointer(&icon), unsafe.Pointer(&template), &cat.c[0])
 	return cat
@@ -70,11 +90,77 @@ func (reply *SearchReply) RegisterDepartments(parent *Department) {
 	C.search_reply_register_departments(&reply.r[0], &parent.d[0])
 }
 
+// NewResult creates a new empty result linked to the given category,
+// which must have been registered with this reply.
+//
+// The result is a *ResultBuilder, so setting its attributes does not
+// call into the scopes runtime.
+func (reply *SearchReply) NewResult(category *Category) ResultSetter {
+	if !category.isRegistered() {
+		panic("Category " + category.Id() + " is not registered with a SearchReply")
+	}
+	return NewResultBuilder(category)
+}
+
 // Push sends a search result to the client.
-func (reply *SearchReply) Push(result *CategorisedResult) error {
+//
+// The result must be a *ResultBuilder or *CategorisedResult, as
+// returned by NewResult, NewResultBuilder or NewCategorisedResult.
+// ErrReplyClosed is returned if the query has finished or been
+// cancelled.
+func (reply *SearchReply) Push(result ResultSetter) error {
+	if _, ok := result.(*ResultBuilder); ok {
+		return reply.PushAll(result)
+	}
+	res, ok := result.(*CategorisedResult)
+	if !ok {
+		return newError(ErrInvalidArgument, fmt.Sprintf("SearchReply can not push results of type %T", result))
+	}
+	if atomic.LoadInt32(&reply.closed) != 0 {
+		return ErrReplyClosed
+	}
 	var errorString *C.char
-	C.search_reply_push(&reply.r[0], result.result, &errorString)
-	return checkError(errorString)
+	pushed := C.search_reply_push(&reply.r[0], res.result, &errorString)
+	if err := checkError(errorString); err != nil {
+		return err
+	}
+	if pushed == 0 {
+		return ErrReplyClosed
+	}
+	return nil
+}
+
+// PushAll sends several search results to the client with a single
+// call into the scopes runtime.
+//
+// Each result must be a *ResultBuilder.  If the query finishes or is
+// cancelled part way through, the remaining results are dropped and
+// ErrReplyClosed is returned.
+func (reply *SearchReply) PushAll(results ...ResultSetter) error {
+	if len(results) == 0 {
+		return nil
+	}
+	categories, data, err := encodeResults(results)
+	if err != nil {
+		return err
+	}
+	if atomic.LoadInt32(&reply.closed) != 0 {
+		return ErrReplyClosed
+	}
+	cats := make([]C.SharedPtrData, len(categories))
+	for i, cat := range categories {
+		cats[i] = cat.c
+	}
+	var errorString *C.char
+	pushed := C.search_reply_push_all(&reply.r[0], &cats[0], unsafe.Pointer(&data), C.int(len(cats)), &errorString)
+	runtime.KeepAlive(categories)
+	if err := checkError(errorString); err != nil {
+		return err
+	}
+	if pushed == 0 {
+		return ErrReplyClosed
+	}
+	return nil
 }
 
 // PushFilters sends the set of filters and their state to the client.
@@ -87,12 +173,15 @@ func (reply *SearchReply) PushFilters(filters []Filter, state FilterState) error
 	if data, err := json.Marshal(filterData); err == nil {
 		filtersJson = string(data)
 	} else {
-		return err
+		return serializationError(err)
 	}
 	if data, err := json.Marshal(state); err == nil {
 		stateJson = string(data)
 	} else {
-		return err
+		return serializationError(err)
+	}
+	if atomic.LoadInt32(&reply.closed) != 0 {
+		return ErrReplyClosed
 	}
 	var errorString *C.char
 	C.search_reply_push_filters(&reply.r[0], unsafe.Pointer(&filtersJson), unsafe.Pointer(&stateJson), &errorString)
@@ -102,6 +191,9 @@ func (reply *SearchReply) PushFilters(filters []Filter, state FilterState) error
 // PreviewReply is used to send result previews to the client.
 type PreviewReply struct {
 	r C.SharedPtrData
+
+	finish sync.Once
+	closed int32
 }
 
 func makePreviewReply(replyData *C.uintptr_t) *PreviewReply {
@@ -119,9 +211,13 @@ func finalizePreviewReply(reply *PreviewReply) {
 // attributes will be pushed to this reply.
 //
 // This is called automatically if a scope's Preview method completes
-// without error.
+// without error.  Only the first call to Finished or Error has any
+// effect.
 func (reply *PreviewReply) Finished() {
-	C.preview_reply_finished(&reply.r[0])
+	reply.finish.Do(func() {
+		atomic.StoreInt32(&reply.closed, 1)
+		C.preview_reply_finished(&reply.r[0])
+	})
 }
 
 // Error is called to indicate that the preview generation could not
@@ -130,20 +226,28 @@ func (reply *PreviewReply) Finished() {
 // This is called automatically if a scope's Preview method completes
 // with an error.
 func (reply *PreviewReply) Error(err error) {
-	errString := err.Error()
-	C.preview_reply_error(&reply.r[0], unsafe.Pointer(&errString))
+	reply.finish.Do(func() {
+		atomic.StoreInt32(&reply.closed, 1)
+		errString := err.Error()
+		C.preview_reply_error(&reply.r[0], unsafe.Pointer(&errString))
+	})
 }
 
 // PushWidgets sends one or more preview widgets to the client.
+//
+// ErrReplyClosed is returned if the preview has already finished.
 func (reply *PreviewReply) PushWidgets(widgets ...PreviewWidget) error {
 	widget_data := make([]string, len(widgets))
 	for i, w := range widgets {
 		data, err := w.data()
 		if err != nil {
-			return err
+			return serializationError(err)
 		}
 		widget_data[i] = string(data)
 	}
+	if atomic.LoadInt32(&reply.closed) != 0 {
+		return ErrReplyClosed
+	}
 	var errorString *C.char
 	C.preview_reply_push_widgets(&reply.r[0], unsafe.Pointer(&widget_data[0]), C.int(len(widget_data)), &errorString)
 	return checkError(errorString)
@@ -158,7 +262,10 @@ func (reply *PreviewReply) PushWidgets(widgets ...PreviewWidget) error {
 func (reply *PreviewReply) PushAttr(attr string, value interface{}) error {
 	data, err := json.Marshal(value)
 	if err != nil {
-		return err
+		return serializationError(err)
+	}
+	if atomic.LoadInt32(&reply.closed) != 0 {
+		return ErrReplyClosed
 	}
 	json_value := string(data)
 	var errorString *C.char
@@ -1,3 +1,5 @@
+// +build cgo,!purego
+
 #include <stdexcept>
 #include <cstring>
 
@@ -29,7 +31,19 @@ void *result_get_attr(_Result *res, void *attr, int *length, char **error) {
         Variant v = reinterpret_cast<Result*>(res)->value(from_gostring(attr));
         json_data = v.serialize_json();
     } catch (const std::exception &e) {
-        *error = strdup(e.what());
+        *error = exception_string(e);
+        return nullptr;
+    }
+    return as_bytes(json_data, length);
+}
+
+void *result_get_attrs(_Result *res, int *length, char **error) {
+    std::string json_data;
+    try {
+        VariantMap data = reinterpret_cast<Result*>(res)->serialize();
+        json_data = data["attrs"].serialize_json();
+    } catch (const std::exception &e) {
+        *error = exception_string(e);
         return nullptr;
     }
     return as_bytes(json_data, length);
@@ -40,10 +54,38 @@ void result_set_attr(_Result *res, void *attr, void *json_value, char **error) {
         Variant v = Variant::deserialize_json(from_gostring(json_value));
         (*reinterpret_cast<Result*>(res))[from_gostring(attr)] = v;
     } catch (const std::exception &e) {
-        *error = strdup(e.what());
+        *error = exception_string(e);
     }
 }
 
 void result_set_intercept_activation(_Result *res) {
     reinterpret_cast<Result*>(res)->set_intercept_activation();
 }
+
+namespace gounityscopes {
+namespace internal {
+
+std::vector<CategorisedResult> build_results(SharedPtrData *categories, void *results_json, int count) {
+    VariantArray data = Variant::deserialize_json(from_gostring(results_json)).get_array();
+    if (data.size() != static_cast<std::size_t>(count)) {
+        throw std::invalid_argument("Number of results does not match number of categories");
+    }
+    std::vector<CategorisedResult> results;
+    results.reserve(count);
+    for (int i = 0; i < count; i++) {
+        VariantMap res = data[i].get_dict();
+        CategorisedResult result(get_ptr<const Category>(categories[i]));
+        for (const auto &attr : res["attrs"].get_dict()) {
+            result[attr.first] = attr.second;
+        }
+        auto intercept = res.find("intercept_activation");
+        if (intercept != res.end() && intercept->second.get_bool()) {
+            result.set_intercept_activation();
+        }
+        results.push_back(result);
+    }
+    return results;
+}
+
+}
+}
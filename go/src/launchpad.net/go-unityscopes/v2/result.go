@@ -1,3 +1,5 @@
+// +build cgo,!purego
+
 package scopes
 
 // #include <stdlib.h>
@@ -18,16 +20,28 @@ func makeResult(res *C._Result) *Result {
 	result := new(Result)
 	runtime.SetFinalizer(result, finalizeResult)
 	result.result = res
+	trackObject()
 	return result
 }
 
 func finalizeResult(res *Result) {
 	if res.result != nil {
 		C.destroy_result(res.result)
+		untrackObject()
 	}
 	res.result = nil
 }
 
+// Close releases the native result held by this Result.
+//
+// It is safe to call Close more than once, and the result must not
+// be used afterwards.  If Close is not called, the result is
+// released when the Result is garbage collected.
+func (res *Result) Close() error {
+	finalizeResult(res)
+	return nil
+}
+
 // Get returns the named result attribute.
 //
 // The value is decoded into the variable pointed to by the second
@@ -44,7 +58,28 @@ func (res *Result) Get(attr string, value interface{}) error {
 		return err
 	}
 	defer C.free(data)
-	return json.Unmarshal(C.GoBytes(data, length), value)
+	return serializationError(json.Unmarshal(C.GoBytes(data, length), value))
+}
+
+// Attributes returns all attributes of the result, keyed by name.
+//
+// An error is returned if the result can not be serialized, such as
+// when its URI has not been set.
+func (res *Result) Attributes() (map[string]interface{}, error) {
+	var (
+		length      C.int
+		errorString *C.char
+	)
+	data := C.result_get_attrs(res.result, &length, &errorString)
+	if err := checkError(errorString); err != nil {
+		return nil, err
+	}
+	defer C.free(data)
+	var attrs map[string]interface{}
+	if err := json.Unmarshal(C.GoBytes(data, length), &attrs); err != nil {
+		return nil, serializationError(err)
+	}
+	return attrs, nil
 }
 
 // Set sets the named result attribute.
@@ -54,7 +89,7 @@ func (res *Result) Get(attr string, value interface{}) error {
 func (res *Result) Set(attr string, value interface{}) error {
 	data, err := json.Marshal(value)
 	if err != nil {
-		return err
+		return serializationError(err)
 	}
 	stringValue := string(data)
 
@@ -73,55 +108,6 @@ func (res *Result) SetInterceptActivation() {
 	C.result_set_intercept_activation(res.result)
 }
 
-// SetURI sets the "uri" attribute of the result.
-func (res *Result) SetURI(uri string) error {
-	return res.Set("uri", uri)
-}
-
-// SetTitle sets the "title" attribute of the result.
-func (res *Result) SetTitle(title string) error {
-	return res.Set("title", title)
-}
-
-// SetArt sets the "art" attribute of the result.
-func (res *Result) SetArt(art string) error {
-	return res.Set("art", art)
-}
-
-// SetDndURI sets the "dnd_uri" attribute of the result.
-func (res *Result) SetDndURI(uri string) error {
-	return res.Set("dnd_uri", uri)
-}
-
-func (res *Result) getString(attr string) string {
-	var value string
-	if err := res.Get(attr, &value); err != nil {
-		return ""
-	}
-	return value
-}
-
-// URI returns the "uri" attribute of the result if set, or an empty string.
-func (res *Result) URI() string {
-	return res.getString("uri")
-}
-
-// Title returns the "title" attribute of the result if set, or an empty string.
-func (res *Result) Title() string {
-	return res.getString("title")
-}
-
-// Art returns the "art" attribute of the result if set, or an empty string.
-func (res *Result) Art() string {
-	return res.getString("art")
-}
-
-// DndURI returns the "dnd_uri" attribute of the result if set, or an
-// empty string.
-func (res *Result) DndURI() string {
-	return res.getString("dnd_uri")
-}
-
 // CategorisedResult represents a result linked to a particular category.
 //
 // CategorisedResult embeds Result, so all of its attribute
@@ -132,10 +118,16 @@ type CategorisedResult struct {
 
 // NewCategorisedResult creates a new empty result linked to the given
 // category.
+//
+// The category must have been registered with a SearchReply.
 func NewCategorisedResult(category *Category) *CategorisedResult {
+	if !category.isRegistered() {
+		panic("Category " + category.Id() + " is not registered with a SearchReply")
+	}
 	res := new(CategorisedResult)
 	runtime.SetFinalizer(res, finalizeCategorisedResult)
 	res.result = C.new_categorised_result(&category.c[0])
+	trackObject()
 	return res
 }
 
@@ -0,0 +1,64 @@
+package scopes
+
+// Result is defined separately for cgo builds, in result.go, and
+// builds without cgo, in result_nocgo.go.  This file holds the
+// accessors they share.
+
+// NewResult creates a new empty result that is not linked to a
+// category.
+//
+// It is intended for tests and tools that call a scope's Preview,
+// Activate or PerformAction methods directly.  Scopes should create
+// search results with SearchReplier.NewResult.
+func NewResult() *Result {
+	return newTestingResult()
+}
+
+// SetURI sets the "uri" attribute of the result.
+func (res *Result) SetURI(uri string) error {
+	return res.Set("uri", uri)
+}
+
+// SetTitle sets the "title" attribute of the result.
+func (res *Result) SetTitle(title string) error {
+	return res.Set("title", title)
+}
+
+// SetArt sets the "art" attribute of the result.
+func (res *Result) SetArt(art string) error {
+	return res.Set("art", art)
+}
+
+// SetDndURI sets the "dnd_uri" attribute of the result.
+func (res *Result) SetDndURI(uri string) error {
+	return res.Set("dnd_uri", uri)
+}
+
+func (res *Result) getString(attr string) string {
+	var value string
+	if err := res.Get(attr, &value); err != nil {
+		return ""
+	}
+	return value
+}
+
+// URI returns the "uri" attribute of the result if set, or an empty string.
+func (res *Result) URI() string {
+	return res.getString("uri")
+}
+
+// Title returns the "title" attribute of the result if set, or an empty string.
+func (res *Result) Title() string {
+	return res.getString("title")
+}
+
+// Art returns the "art" attribute of the result if set, or an empty string.
+func (res *Result) Art() string {
+	return res.getString("art")
+}
+
+// DndURI returns the "dnd_uri" attribute of the result if set, or an
+// empty string.
+func (res *Result) DndURI() string {
+	return res.getString("dnd_uri")
+}
@@ -0,0 +1,149 @@
+package scopes
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ResultBuilder builds a search result in Go memory.
+//
+// Unlike CategorisedResult, setting attributes on a ResultBuilder
+// does not call into the scopes runtime.  The result is only
+// constructed when it is pushed, and SearchReply.PushAll pushes any
+// number of builders with a single call into the runtime.
+type ResultBuilder struct {
+	category            *Category
+	attrs               map[string]json.RawMessage
+	interceptActivation bool
+}
+
+// NewResultBuilder creates a new empty result linked to the given
+// category.
+func NewResultBuilder(category *Category) *ResultBuilder {
+	return &ResultBuilder{
+		category: category,
+		attrs:    make(map[string]json.RawMessage),
+	}
+}
+
+// Category returns the category the result belongs to.
+func (res *ResultBuilder) Category() *Category {
+	return res.category
+}
+
+// Get returns the named result attribute.
+//
+// The value is decoded into the variable pointed to by the second
+// argument.  If the attribute does not exist, an error is returned.
+func (res *ResultBuilder) Get(attr string, value interface{}) error {
+	data, ok := res.attrs[attr]
+	if !ok {
+		return newError(ErrInvalidArgument, fmt.Sprintf("Result attribute %q does not exist", attr))
+	}
+	return serializationError(json.Unmarshal(data, value))
+}
+
+// Set sets the named result attribute.
+//
+// An error is returned if the value can not be serialized to JSON.
+func (res *ResultBuilder) Set(attr string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return serializationError(err)
+	}
+	res.attrs[attr] = data
+	return nil
+}
+
+// Attributes returns all attributes of the result, keyed by name.
+func (res *ResultBuilder) Attributes() (map[string]interface{}, error) {
+	attrs := make(map[string]interface{}, len(res.attrs))
+	for name, data := range res.attrs {
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, serializationError(err)
+		}
+		attrs[name] = value
+	}
+	return attrs, nil
+}
+
+// SetURI sets the "uri" attribute of the result.
+func (res *ResultBuilder) SetURI(uri string) error {
+	return res.Set("uri", uri)
+}
+
+// SetTitle sets the "title" attribute of the result.
+func (res *ResultBuilder) SetTitle(title string) error {
+	return res.Set("title", title)
+}
+
+// SetArt sets the "art" attribute of the result.
+func (res *ResultBuilder) SetArt(art string) error {
+	return res.Set("art", art)
+}
+
+// SetDndURI sets the "dnd_uri" attribute of the result.
+func (res *ResultBuilder) SetDndURI(uri string) error {
+	return res.Set("dnd_uri", uri)
+}
+
+// SetInterceptActivation marks this result as needing custom
+// activation handling.
+func (res *ResultBuilder) SetInterceptActivation() {
+	res.interceptActivation = true
+}
+
+// builtResult is the serialized form of a ResultBuilder passed to
+// the scopes runtime.
+type builtResult struct {
+	Attrs               map[string]json.RawMessage `json:"attrs"`
+	InterceptActivation bool                       `json:"intercept_activation,omitempty"`
+}
+
+// encodeResults serializes a batch of results for the scopes runtime,
+// returning the category of each result and a JSON array holding
+// their attributes.
+func encodeResults(results []ResultSetter) ([]*Category, string, error) {
+	categories := make([]*Category, len(results))
+	data := make([]builtResult, len(results))
+	for i, result := range results {
+		res, ok := result.(*ResultBuilder)
+		if !ok {
+			return nil, "", newError(ErrInvalidArgument, fmt.Sprintf("SearchReply can not push results of type %T", result))
+		}
+		if !res.category.isRegistered() {
+			return nil, "", newError(ErrInvalidArgument, fmt.Sprintf("Category %s is not registered with a SearchReply", res.category.Id()))
+		}
+		categories[i] = res.category
+		data[i] = builtResult{res.attrs, res.interceptActivation}
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, "", serializationError(err)
+	}
+	return categories, string(encoded), nil
+}
+
+// batchPusher is implemented by SearchReplier implementations that can
+// push several results at once.
+type batchPusher interface {
+	PushAll(results ...ResultSetter) error
+}
+
+// PushResults pushes the given results to reply.
+//
+// If reply supports it, the results are pushed together with a single
+// call to its PushAll method.  Otherwise they are pushed one at a time
+// with reply.Push.
+func PushResults(reply SearchReplier, results ...ResultSetter) error {
+	if batch, ok := reply.(batchPusher); ok {
+		return batch.PushAll(results...)
+	}
+	for _, result := range results {
+		if err := reply.Push(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
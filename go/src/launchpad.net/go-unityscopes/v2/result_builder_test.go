@@ -0,0 +1,179 @@
+package scopes_test
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+	"launchpad.net/go-unityscopes/v2"
+)
+
+func (s *S) TestResultBuilder(c *C) {
+	cat := scopes.NewCategory("apps", "Apps", "", "")
+	res := scopes.NewResultBuilder(cat)
+	c.Check(res.Category(), Equals, cat)
+	c.Check(res.SetURI("http://example.com"), IsNil)
+	c.Check(res.SetTitle("The title"), IsNil)
+	c.Check(res.Set("count", 42), IsNil)
+
+	var uri string
+	c.Check(res.Get("uri", &uri), IsNil)
+	c.Check(uri, Equals, "http://example.com")
+	var count int
+	c.Check(res.Get("count", &count), IsNil)
+	c.Check(count, Equals, 42)
+	c.Check(res.Get("missing", &uri), ErrorMatches, `Result attribute "missing" does not exist`)
+
+	attrs, err := res.Attributes()
+	c.Check(err, IsNil)
+	c.Check(attrs, DeepEquals, map[string]interface{}{
+		"uri":   "http://example.com",
+		"title": "The title",
+		"count": 42.0,
+	})
+
+	c.Check(res.Set("bad", &unserializable{}), ErrorMatches, ".*Can not marshal to JSON")
+}
+
+func (s *S) TestBuildResults(c *C) {
+	cat := scopes.NewTestingCategory("apps")
+	results := make([]scopes.ResultSetter, 3)
+	for i := range results {
+		res := scopes.NewResultBuilder(cat)
+		c.Check(res.SetURI("http://example.com"), IsNil)
+		res.SetInterceptActivation()
+		results[i] = res
+	}
+	c.Check(scopes.BuildTestingResults(results), IsNil)
+
+	// Categories must be backed by the scopes runtime
+	unregistered := scopes.NewResultBuilder(scopes.NewCategory("other", "Other", "", ""))
+	c.Check(scopes.BuildTestingResults([]scopes.ResultSetter{unregistered}), ErrorMatches, "Category other is not registered with a SearchReply")
+
+	// Only builders can be pushed in a batch
+	c.Check(scopes.BuildTestingResults([]scopes.ResultSetter{scopes.NewTestingResult()}), ErrorMatches, `SearchReply can not push results of type \*scopes.Result`)
+}
+
+// batchSearchReply records how results are pushed to it.
+type batchSearchReply struct {
+	testSearchReply
+	batches []int
+}
+
+func (reply *batchSearchReply) NewResult(category *scopes.Category) scopes.ResultSetter {
+	return scopes.NewResultBuilder(category)
+}
+
+func (reply *batchSearchReply) PushAll(results ...scopes.ResultSetter) error {
+	reply.batches = append(reply.batches, len(results))
+	for _, result := range results {
+		var title string
+		result.(*scopes.ResultBuilder).Get("title", &title)
+		reply.titles = append(reply.titles, title)
+	}
+	return nil
+}
+
+func (s *S) TestPushResults(c *C) {
+	cat := scopes.NewCategory("apps", "Apps", "", "")
+	var results []scopes.ResultSetter
+	for _, title := range []string{"one", "two", "three"} {
+		res := scopes.NewTestingResult()
+		c.Check(res.SetTitle(title), IsNil)
+		results = append(results, res)
+	}
+
+	// Replies without PushAll have each result pushed in turn
+	reply := &testSearchReply{}
+	c.Check(scopes.PushResults(reply, results...), IsNil)
+	c.Check(reply.titles, DeepEquals, []string{"one", "two", "three"})
+
+	batch := &batchSearchReply{}
+	results = results[:0]
+	for _, title := range []string{"one", "two", "three"} {
+		res := batch.NewResult(cat)
+		c.Check(res.SetTitle(title), IsNil)
+		results = append(results, res)
+	}
+	c.Check(scopes.PushResults(batch, results...), IsNil)
+	c.Check(batch.batches, DeepEquals, []int{3})
+	c.Check(batch.titles, DeepEquals, []string{"one", "two", "three"})
+}
+
+type batchScope struct {
+	recordedScope
+}
+
+func (sc *batchScope) Search(query *scopes.CannedQuery, metadata *scopes.SearchMetadata, reply scopes.SearchReplier, cancelled <-chan bool) error {
+	cat := reply.RegisterCategory("cat", "Category", "", "")
+	var results []scopes.ResultSetter
+	for _, title := range []string{"one", "two"} {
+		res := reply.NewResult(cat)
+		res.SetTitle(title)
+		results = append(results, res)
+	}
+	return scopes.PushResults(reply, results...)
+}
+
+func (s *S) TestPushResultsThroughMiddleware(c *C) {
+	var buf bytes.Buffer
+	var counted int
+	scope := scopes.Chain(&batchScope{},
+		scopes.NewRecorder(&buf).Middleware(),
+		scopes.NewResultCache(0, 0).Middleware(),
+		scopes.ResultCountMiddleware(func(query *scopes.CannedQuery, count int) {
+			counted = count
+		}))
+
+	// Batches are passed through the middleware intact
+	reply := &batchSearchReply{}
+	metadata := scopes.NewSearchMetadata(0, "us", "phone")
+	c.Check(scope.Search(scopes.NewCannedQuery("scope", "", ""), metadata, reply, nil), IsNil)
+	c.Check(reply.batches, DeepEquals, []int{2})
+	c.Check(reply.titles, DeepEquals, []string{"one", "two"})
+	c.Check(counted, Equals, 2)
+
+	entries, err := scopes.ReadRecording(&buf)
+	c.Assert(err, IsNil)
+	var kinds []string
+	for _, entry := range entries {
+		kinds = append(kinds, entry.Kind)
+	}
+	c.Check(kinds, DeepEquals, []string{"search", "category", "result", "result", "finished"})
+}
+
+const benchmarkResults = 100
+
+func setBenchmarkAttrs(res scopes.ResultSetter) {
+	res.SetURI("application:///foo.desktop")
+	res.SetTitle("Foo")
+	res.SetArt("/usr/share/icons/foo.png")
+	res.SetDndURI("application:///foo.desktop")
+	res.Set("app", map[string]string{"name": "Foo"})
+}
+
+// BenchmarkCategorisedResults builds results with one call into the
+// scopes runtime per attribute.
+func (s *S) BenchmarkCategorisedResults(c *C) {
+	cat := scopes.NewTestingCategory("apps")
+	for i := 0; i < c.N; i++ {
+		for j := 0; j < benchmarkResults; j++ {
+			setBenchmarkAttrs(scopes.NewCategorisedResult(cat))
+		}
+	}
+}
+
+// BenchmarkResultBuilders builds the same results in Go, then builds
+// them in the scopes runtime with a single call.
+func (s *S) BenchmarkResultBuilders(c *C) {
+	cat := scopes.NewTestingCategory("apps")
+	for i := 0; i < c.N; i++ {
+		results := make([]scopes.ResultSetter, benchmarkResults)
+		for j := range results {
+			results[j] = scopes.NewResultBuilder(cat)
+			setBenchmarkAttrs(results[j])
+		}
+		if err := scopes.BuildTestingResults(results); err != nil {
+			c.Fatal(err)
+		}
+	}
+}
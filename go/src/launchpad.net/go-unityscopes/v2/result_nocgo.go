@@ -0,0 +1,99 @@
+// +build !cgo purego
+
+package scopes
+
+import (
+	"encoding/json"
+)
+
+// Result represents a result from the scope
+type Result struct {
+	attrs               map[string]json.RawMessage
+	interceptActivation bool
+}
+
+// Close releases the native result held by this Result.
+//
+// Without cgo there is nothing to release, so Close does nothing.
+func (res *Result) Close() error {
+	return nil
+}
+
+// Get returns the named result attribute.
+//
+// The value is decoded into the variable pointed to by the second
+// argument.  If the types do not match, an error will be returned.
+//
+// If the attribute does not exist, an error is returned.
+func (res *Result) Get(attr string, value interface{}) error {
+	data, ok := res.attrs[attr]
+	if !ok {
+		return newError(ErrInvalidArgument, "Result: attribute "+attr+" does not exist")
+	}
+	return serializationError(json.Unmarshal(data, value))
+}
+
+// Attributes returns all attributes of the result, keyed by name.
+//
+// An error is returned if the result can not be serialized, such as
+// when its URI has not been set.
+func (res *Result) Attributes() (map[string]interface{}, error) {
+	if _, ok := res.attrs["uri"]; !ok {
+		return nil, newError(ErrInvalidArgument, "Result: uri must not be empty")
+	}
+	attrs := make(map[string]interface{}, len(res.attrs))
+	for name, data := range res.attrs {
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, serializationError(err)
+		}
+		attrs[name] = value
+	}
+	return attrs, nil
+}
+
+// Set sets the named result attribute.
+//
+// An error may be returned if the value can not be stored, or if
+// there is any other problems updating the result.
+func (res *Result) Set(attr string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return serializationError(err)
+	}
+	if res.attrs == nil {
+		res.attrs = make(map[string]json.RawMessage)
+	}
+	res.attrs[attr] = data
+	return nil
+}
+
+// SetInterceptActivation marks this result as needing custom activation handling.
+//
+// By default, results are activated by the client directly (e.g. by
+// running the application associated with the result URI).  For
+// results with this flag set though, the scope will be asked to
+// perform activation and should implement the Activate method.
+func (res *Result) SetInterceptActivation() {
+	res.interceptActivation = true
+}
+
+// CategorisedResult represents a result linked to a particular category.
+//
+// CategorisedResult embeds Result, so all of its attribute
+// manipulation methods can be used on variables of this type.
+type CategorisedResult struct {
+	Result
+	category *Category
+}
+
+// NewCategorisedResult creates a new empty result linked to the given
+// category.
+//
+// The category must have been registered with a SearchReply.
+func NewCategorisedResult(category *Category) *CategorisedResult {
+	if !category.isRegistered() {
+		panic("Category " + category.Id() + " is not registered with a SearchReply")
+	}
+	return &CategorisedResult{category: category}
+}
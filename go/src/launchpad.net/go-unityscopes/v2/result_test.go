@@ -97,3 +97,16 @@ func (s *S) TestResultGetBadValue(c *C) {
 	var attr string
 	c.Check(r.Get("bad_attribute", &attr), Not(Equals), nil)
 }
+
+func (s *S) TestResultAttributes(c *C) {
+	r := scopes.NewTestingResult()
+	c.Check(r.SetURI("http://example.com"), IsNil)
+	c.Check(r.SetTitle("The title"), IsNil)
+	c.Check(r.Set("count", 42), IsNil)
+
+	attrs, err := r.Attributes()
+	c.Check(err, IsNil)
+	c.Check(attrs["uri"], Equals, "http://example.com")
+	c.Check(attrs["title"], Equals, "The title")
+	c.Check(attrs["count"], Equals, float64(42))
+}
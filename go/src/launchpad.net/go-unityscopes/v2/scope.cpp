@@ -1,3 +1,5 @@
+// +build cgo,!purego
+
 #include <unity/scopes/Category.h>
 extern "C" {
 #include "_cgo_export.h"
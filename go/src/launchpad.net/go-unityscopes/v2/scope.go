@@ -0,0 +1,243 @@
+package scopes
+
+// This file holds the parts of the scope API that do not call into
+// the scopes runtime, and so are shared by cgo builds and builds
+// without cgo.  The runtime itself is in unityscope.go, and
+// unityscope_nocgo.go stands in for it when cgo is disabled.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// Scope defines the interface that scope implementations must implement
+type Scope interface {
+	SetScopeBase(base *ScopeBase)
+	Search(query *CannedQuery, metadata *SearchMetadata, reply SearchReplier, cancelled <-chan bool) error
+	Preview(result *Result, metadata *ActionMetadata, reply PreviewReplier, cancelled <-chan bool) error
+}
+
+// Activator is an interface that should be implemented by scopes that
+// need to handle result activation directly.
+type Activator interface {
+	Scope
+	Activate(result *Result, metadata *ActionMetadata) (*ActivationResponse, error)
+}
+
+// PerformActioner is an interface that should be implemented by
+// scopes that need to handle preview actions directly.
+type PerformActioner interface {
+	Scope
+	PerformAction(result *Result, metadata *ActionMetadata, widgetId, actionId string) (*ActivationResponse, error)
+}
+
+// SettingsDefiner is an interface that should be implemented by
+// scopes that describe their settings in Go.
+//
+// When the scope executable is run with the --write-settings flag,
+// Run writes the definition out as a settings .ini file instead of
+// starting the scope.
+type SettingsDefiner interface {
+	Scope
+	SettingsDefinition() *SettingsDefinition
+}
+
+// SettingsNotifier is an interface that should be implemented by
+// scopes that need to know when the user changes their settings.
+//
+// The scopes runtime does not report settings changes, so they are
+// found by polling: before each Search, Preview, Activate or
+// PerformAction call, the settings are read from the runtime and
+// compared with the previous read.  Only when they differ are they
+// decoded, validated against the scope's SettingsDefinition, and
+// SettingsChanged called, so the scope can reload its settings
+// through ScopeBase.Settings.  Scopes should keep the decoded values
+// rather than calling ScopeBase.Settings for every request.
+type SettingsNotifier interface {
+	Scope
+	SettingsChanged()
+}
+
+// ScopeBase exposes information about the scope including settings
+// and various directories available for use.
+type ScopeBase struct {
+	// b is the runtime's scope base, or nil for a base created
+	// with NewScopeBase.
+	b unsafe.Pointer
+
+	scopeDir string
+	cacheDir string
+	tmpDir   string
+
+	// settings holds the scope's settings when cached is set:
+	// those read by the most recent poll for the bases of
+	// SettingsNotifier scopes, or those given to SetSettings for a
+	// base created with NewScopeBase.
+	settingsLock sync.Mutex
+	settings     []byte
+	cached       bool
+}
+
+// NewScopeBase creates a ScopeBase that is not backed by the scopes
+// runtime, using the given directories.  The base starts with empty
+// settings.
+//
+// It is intended for tests and tools that drive a scope without the
+// runtime, and can be used whether or not the package is built with
+// cgo.
+func NewScopeBase(scopeDir, cacheDir, tmpDir string) *ScopeBase {
+	return &ScopeBase{
+		scopeDir: scopeDir,
+		cacheDir: cacheDir,
+		tmpDir:   tmpDir,
+		settings: []byte("{}"),
+		cached:   true,
+	}
+}
+
+// Settings returns the scope's settings.  The settings will be
+// decoded into the given value according to the same rules used by
+// json.Unmarshal().
+//
+// For scopes that implement SettingsNotifier, the settings read by
+// the most recent poll are decoded, so they match what the scope was
+// notified about and the runtime is not asked for them again.
+func (b *ScopeBase) Settings(value interface{}) error {
+	b.settingsLock.Lock()
+	data, cached := b.settings, b.cached
+	b.settingsLock.Unlock()
+	if !cached {
+		data = b.settingsData()
+	}
+	return json.Unmarshal(data, value)
+}
+
+// SetSettings replaces the settings of a ScopeBase created with
+// NewScopeBase with the JSON encoding of value, as if the user had
+// changed them.  The scope is not notified of the change.
+//
+// The settings of a base backed by the scopes runtime can only be
+// changed by the user, so ErrLogic is returned for them.
+func (b *ScopeBase) SetSettings(value interface{}) error {
+	if b.b != nil {
+		return newError(ErrLogic, "The settings of a scope run by the scopes runtime can not be set")
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return serializationError(err)
+	}
+	b.settingsLock.Lock()
+	b.settings = data
+	b.cached = true
+	b.settingsLock.Unlock()
+	return nil
+}
+
+// NewCategory creates a category that is not backed by the scopes
+// runtime.
+//
+// It is intended for SearchReplier implementations other than
+// SearchReply, such as test doubles.  Scopes should register their
+// categories with SearchReplier.RegisterCategory instead.
+func NewCategory(id, title, icon, template string) *Category {
+	return &Category{
+		id:       id,
+		title:    title,
+		icon:     icon,
+		template: template,
+	}
+}
+
+// Id returns the identifier of the category.
+func (cat *Category) Id() string {
+	return cat.id
+}
+
+// Title returns the title of the category.
+func (cat *Category) Title() string {
+	return cat.title
+}
+
+// Icon returns the icon of the category.
+func (cat *Category) Icon() string {
+	return cat.icon
+}
+
+// Template returns the JSON renderer template of the category.
+func (cat *Category) Template() string {
+	return cat.template
+}
+
+var (
+	runtimeConfig = flag.String("runtime", "", "The runtime configuration file for the Unity Scopes library")
+	scopeConfig   = flag.String("scope", "", "The scope configuration file for the Unity Scopes library")
+	settingsFile  = flag.String("write-settings", "", "Write the scope's settings definition to the given .ini file and exit")
+	queryTimeout  = flag.Duration("query-timeout", 0, "Cancel Search and Preview requests that run for longer than this duration")
+)
+
+// SetQueryTimeout sets the time a Search or Preview request may run
+// for before it is cancelled.  A zero duration disables the timeout.
+//
+// When a request reaches its deadline, the cancelled channel and the
+// metadata's Context are both cancelled, a warning is logged, and the
+// reply is finished with whatever has already been pushed.  The
+// timeout can also be set with the --query-timeout flag.
+func SetQueryTimeout(timeout time.Duration) {
+	*queryTimeout = timeout
+}
+
+// writeSettings writes the scope's settings definition to the file
+// given with the --write-settings flag.
+func writeSettings(scope Scope) error {
+	definition := settingsDefinition(scope)
+	if definition == nil {
+		return errors.New("Scope does not provide a settings definition")
+	}
+	return definition.WriteIniFile(*settingsFile)
+}
+
+// settingsDefinition returns the scope's settings definition, or nil
+// if it does not provide one.
+func settingsDefinition(scope Scope) *SettingsDefinition {
+	if definer, ok := scope.(SettingsDefiner); ok {
+		return definer.SettingsDefinition()
+	}
+	return nil
+}
+
+// newQueryContext returns a context that is cancelled when the shell
+// cancels a query through the given channel, or when the query runs
+// for longer than timeout if it is non-zero.
+//
+// The returned channel is closed when the context is done, so unlike
+// the shell's cancel channel it can be watched by any number of
+// goroutines.  If the deadline is reached, onDeadline is called after
+// the channel is closed.  The returned function must be called once
+// the query has completed.
+func newQueryContext(cancel <-chan bool, timeout time.Duration, onDeadline func()) (context.Context, <-chan bool, context.CancelFunc) {
+	var ctx context.Context
+	var cancelFunc context.CancelFunc
+	if timeout > 0 {
+		ctx, cancelFunc = context.WithTimeout(context.Background(), timeout)
+	} else {
+		ctx, cancelFunc = context.WithCancel(context.Background())
+	}
+	cancelled := make(chan bool)
+	go func() {
+		select {
+		case <-cancel:
+			cancelFunc()
+		case <-ctx.Done():
+		}
+		close(cancelled)
+		if ctx.Err() == context.DeadlineExceeded && onDeadline != nil {
+			onDeadline()
+		}
+	}()
+	return ctx, cancelled, cancelFunc
+}
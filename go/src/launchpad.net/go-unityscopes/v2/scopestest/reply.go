@@ -0,0 +1,281 @@
+package scopestest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"launchpad.net/go-unityscopes/v2"
+)
+
+var (
+	_ scopes.SearchReplier  = (*SearchReply)(nil)
+	_ scopes.PreviewReplier = (*PreviewReply)(nil)
+)
+
+// SearchReply is a fake search reply that records everything pushed
+// to it.
+//
+// The exported fields should only be inspected once the scope's
+// Search method has returned.
+type SearchReply struct {
+	lock sync.Mutex
+
+	// Categories holds the registered categories in registration order.
+	Categories []*scopes.Category
+	// Results holds the pushed results in push order.
+	Results []*scopes.ResultBuilder
+	// Filters and FilterState hold the arguments of the most
+	// recent PushFilters call.
+	Filters     []scopes.Filter
+	FilterState scopes.FilterState
+	// Departments holds the most recently registered department.
+	Departments *scopes.Department
+	// IsFinished is set once Finished or Error has been called,
+	// and Err holds the error passed to Error.
+	IsFinished bool
+	Err        error
+}
+
+// NewSearchReply creates a new empty SearchReply.
+func NewSearchReply() *SearchReply {
+	return new(SearchReply)
+}
+
+// Finished is called to indicate that no further results will be
+// pushed to this reply.
+func (reply *SearchReply) Finished() {
+	reply.lock.Lock()
+	reply.IsFinished = true
+	reply.lock.Unlock()
+}
+
+// Error is called to indicate that search query could not be
+// completed successfully.
+func (reply *SearchReply) Error(err error) {
+	reply.lock.Lock()
+	reply.IsFinished = true
+	reply.Err = err
+	reply.lock.Unlock()
+}
+
+// RegisterCategory registers a new results category.
+//
+// As with the scopes runtime, registering the same category ID twice
+// is a programming error and panics.
+func (reply *SearchReply) RegisterCategory(id, title, icon, template string) *scopes.Category {
+	reply.lock.Lock()
+	defer reply.lock.Unlock()
+	for _, cat := range reply.Categories {
+		if cat.Id() == id {
+			panic(fmt.Sprintf("Category %q is already registered", id))
+		}
+	}
+	if template != "" {
+		var v interface{}
+		if err := json.Unmarshal([]byte(template), &v); err != nil {
+			panic(fmt.Sprintf("Invalid template for category %q: %v", id, err))
+		}
+	}
+	cat := scopes.NewCategory(id, title, icon, template)
+	reply.Categories = append(reply.Categories, cat)
+	return cat
+}
+
+// RegisterDepartments registers the department set to display with
+// the search results.
+func (reply *SearchReply) RegisterDepartments(parent *scopes.Department) {
+	reply.lock.Lock()
+	reply.Departments = parent
+	reply.lock.Unlock()
+}
+
+// NewResult creates a new empty result linked to the given category,
+// which must have been registered with this reply.
+func (reply *SearchReply) NewResult(category *scopes.Category) scopes.ResultSetter {
+	reply.lock.Lock()
+	defer reply.lock.Unlock()
+	if !reply.hasCategory(category) {
+		panic("Category " + category.Id() + " is not registered with this reply")
+	}
+	return scopes.NewResultBuilder(category)
+}
+
+// Push records a search result.
+//
+// The result must be a *scopes.ResultBuilder, as returned by
+// NewResult.  scopes.ErrReplyClosed is returned if the reply has
+// finished, and an error is returned if the result's category was not
+// registered with this reply.
+func (reply *SearchReply) Push(result scopes.ResultSetter) error {
+	res, ok := result.(*scopes.ResultBuilder)
+	if !ok {
+		return fmt.Errorf("SearchReply can not push results of type %T", result)
+	}
+	reply.lock.Lock()
+	defer reply.lock.Unlock()
+	if reply.IsFinished {
+		return scopes.ErrReplyClosed
+	}
+	if !reply.hasCategory(res.Category()) {
+		return errors.New("Result category was not registered with this reply")
+	}
+	reply.Results = append(reply.Results, res)
+	return nil
+}
+
+// PushFilters records the set of filters and their state.
+func (reply *SearchReply) PushFilters(filters []scopes.Filter, state scopes.FilterState) error {
+	reply.lock.Lock()
+	defer reply.lock.Unlock()
+	if reply.IsFinished {
+		return scopes.ErrReplyClosed
+	}
+	reply.Filters = filters
+	reply.FilterState = state
+	return nil
+}
+
+func (reply *SearchReply) hasCategory(category *scopes.Category) bool {
+	for _, cat := range reply.Categories {
+		if cat == category {
+			return true
+		}
+	}
+	return false
+}
+
+// Category returns the registered category with the given ID, or nil.
+func (reply *SearchReply) Category(id string) *scopes.Category {
+	reply.lock.Lock()
+	defer reply.lock.Unlock()
+	for _, cat := range reply.Categories {
+		if cat.Id() == id {
+			return cat
+		}
+	}
+	return nil
+}
+
+// ResultsInCategory returns the pushed results belonging to the
+// category with the given ID, in push order.
+func (reply *SearchReply) ResultsInCategory(id string) []*scopes.ResultBuilder {
+	reply.lock.Lock()
+	defer reply.lock.Unlock()
+	var results []*scopes.ResultBuilder
+	for _, res := range reply.Results {
+		if res.Category().Id() == id {
+			results = append(results, res)
+		}
+	}
+	return results
+}
+
+// PreviewReply is a fake preview reply that records everything
+// pushed to it.
+type PreviewReply struct {
+	lock sync.Mutex
+
+	// Widgets holds the pushed widgets in push order.
+	Widgets []scopes.PreviewWidget
+	// Attrs holds the pushed preview attributes.
+	Attrs map[string]interface{}
+	// Layouts holds the registered column layouts.
+	Layouts []*scopes.ColumnLayout
+	// IsFinished is set once Finished or Error has been called,
+	// and Err holds the error passed to Error.
+	IsFinished bool
+	Err        error
+}
+
+// NewPreviewReply creates a new empty PreviewReply.
+func NewPreviewReply() *PreviewReply {
+	return &PreviewReply{
+		Attrs: make(map[string]interface{}),
+	}
+}
+
+// Finished is called to indicate that no further widgets or
+// attributes will be pushed to this reply.
+func (reply *PreviewReply) Finished() {
+	reply.lock.Lock()
+	reply.IsFinished = true
+	reply.lock.Unlock()
+}
+
+// Error is called to indicate that the preview generation could not
+// be completed successfully.
+func (reply *PreviewReply) Error(err error) {
+	reply.lock.Lock()
+	reply.IsFinished = true
+	reply.Err = err
+	reply.lock.Unlock()
+}
+
+// PushWidgets records one or more preview widgets.
+//
+// Widgets are checked to be serializable, as the scopes runtime
+// would.
+func (reply *PreviewReply) PushWidgets(widgets ...scopes.PreviewWidget) error {
+	reply.lock.Lock()
+	defer reply.lock.Unlock()
+	if reply.IsFinished {
+		return scopes.ErrReplyClosed
+	}
+	for _, w := range widgets {
+		if _, err := json.Marshal(w); err != nil {
+			return err
+		}
+	}
+	reply.Widgets = append(reply.Widgets, widgets...)
+	return nil
+}
+
+// PushAttr records a preview attribute.
+func (reply *PreviewReply) PushAttr(attr string, value interface{}) error {
+	reply.lock.Lock()
+	defer reply.lock.Unlock()
+	if reply.IsFinished {
+		return scopes.ErrReplyClosed
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	reply.Attrs[attr] = decoded
+	return nil
+}
+
+// RegisterLayout records a list of column layouts for the preview.
+//
+// As with the scopes runtime, an error is returned if layouts are
+// registered more than once or after widgets have been pushed.
+func (reply *PreviewReply) RegisterLayout(layout ...*scopes.ColumnLayout) error {
+	reply.lock.Lock()
+	defer reply.lock.Unlock()
+	if reply.Layouts != nil {
+		return errors.New("Column layouts have already been registered")
+	}
+	if len(reply.Widgets) > 0 {
+		return errors.New("Column layouts must be registered before pushing widgets")
+	}
+	reply.Layouts = layout
+	return nil
+}
+
+// Widget returns the pushed widget with the given ID, or nil.
+func (reply *PreviewReply) Widget(id string) scopes.PreviewWidget {
+	reply.lock.Lock()
+	defer reply.lock.Unlock()
+	for _, w := range reply.Widgets {
+		if w.Id() == id {
+			return w
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package scopestest_test
+
+import (
+	"errors"
+
+	. "gopkg.in/check.v1"
+	"launchpad.net/go-unityscopes/v2"
+	"launchpad.net/go-unityscopes/v2/scopestest"
+)
+
+// search is a minimal scope search implementation exercised through
+// the fake reply.
+func search(query *scopes.CannedQuery, reply scopes.SearchReplier) error {
+	cat := reply.RegisterCategory("results", "Results", "", "")
+	for _, title := range []string{"one", "two"} {
+		result := reply.NewResult(cat)
+		result.SetTitle(title + " " + query.QueryString())
+		if err := reply.Push(result); err != nil {
+			return err
+		}
+	}
+
+	dept, err := scopes.NewDepartment("", query, "All")
+	if err != nil {
+		return err
+	}
+	child, err := scopes.NewDepartment("child", query, "Child")
+	if err != nil {
+		return err
+	}
+	dept.AddSubdepartment(child)
+	reply.RegisterDepartments(dept)
+
+	filter := scopes.NewRadioButtonsFilter("filter", "Filter")
+	return reply.PushFilters([]scopes.Filter{filter}, scopes.FilterState{"filter": true})
+}
+
+func (s *S) TestSearchReply(c *C) {
+	reply := scopestest.NewSearchReply()
+	query := scopes.NewCannedQuery("scope", "foo", "")
+	c.Assert(search(query, reply), IsNil)
+	reply.Finished()
+
+	c.Check(reply.IsFinished, Equals, true)
+	c.Check(reply.Err, IsNil)
+	c.Assert(reply.Categories, HasLen, 1)
+	c.Check(reply.Category("results"), Equals, reply.Categories[0])
+	c.Check(reply.Category("missing"), IsNil)
+
+	results := reply.ResultsInCategory("results")
+	c.Assert(results, HasLen, 2)
+	var title string
+	c.Check(results[0].Get("title", &title), IsNil)
+	c.Check(title, Equals, "one foo")
+	c.Check(results[1].Get("title", &title), IsNil)
+	c.Check(title, Equals, "two foo")
+
+	c.Assert(reply.Departments, NotNil)
+	c.Check(reply.Departments.Label(), Equals, "All")
+	c.Check(reply.Departments.HasSubdepartments(), Equals, true)
+	c.Check(reply.Departments.Subdepartments()[0].Query().DepartmentID(), Equals, "child")
+
+	c.Assert(reply.Filters, HasLen, 1)
+	c.Check(reply.FilterState, DeepEquals, scopes.FilterState{"filter": true})
+
+	// Nothing can be pushed once the reply has finished
+	result := scopes.NewResultBuilder(reply.Categories[0])
+	c.Check(reply.Push(result), Equals, scopes.ErrReplyClosed)
+}
+
+func (s *S) TestSearchReplyErrors(c *C) {
+	reply := scopestest.NewSearchReply()
+	reply.RegisterCategory("cat", "Category", "", "")
+	c.Check(func() { reply.RegisterCategory("cat", "Again", "", "") }, PanicMatches, `Category "cat" is already registered`)
+	c.Check(func() { reply.RegisterCategory("bad", "Bad", "", "{") }, PanicMatches, `Invalid template for category "bad".*`)
+
+	other := scopes.NewCategory("cat", "Category", "", "")
+	c.Check(func() { reply.NewResult(other) }, PanicMatches, "Category cat is not registered with this reply")
+	c.Check(reply.Push(scopes.NewResultBuilder(other)), ErrorMatches, "Result category was not registered with this reply")
+	c.Check(reply.Push(scopes.NewResult()), ErrorMatches, `SearchReply can not push results of type \*scopes.Result`)
+
+	reply.Error(errors.New("failed"))
+	c.Check(reply.IsFinished, Equals, true)
+	c.Check(reply.Err, ErrorMatches, "failed")
+}
+
+func (s *S) TestPreviewReply(c *C) {
+	reply := scopestest.NewPreviewReply()
+
+	layout := scopes.NewColumnLayout(1)
+	c.Check(layout.AddColumn("header", "actions"), IsNil)
+	c.Check(reply.RegisterLayout(layout), IsNil)
+	c.Check(reply.RegisterLayout(layout), NotNil)
+
+	header := scopes.NewPreviewWidget("header", "header")
+	header.AddAttributeValue("title", "Title")
+	details := scopes.NewPreviewWidget("details", "expandable")
+	details.AddWidget(scopes.NewPreviewWidget("text", "text"))
+	c.Check(reply.PushWidgets(header, details), IsNil)
+	c.Check(reply.PushAttr("rating", 5), IsNil)
+	reply.Finished()
+
+	c.Assert(reply.Widgets, HasLen, 2)
+	c.Check(reply.Widget("header")["title"], Equals, "Title")
+	c.Check(reply.Widget("details")["widgets"], HasLen, 1)
+	c.Check(reply.Widget("missing"), IsNil)
+	c.Check(reply.Attrs, DeepEquals, map[string]interface{}{"rating": 5.0})
+	c.Check(reply.Layouts, DeepEquals, []*scopes.ColumnLayout{layout})
+
+	c.Check(reply.PushWidgets(header), Equals, scopes.ErrReplyClosed)
+	c.Check(reply.PushAttr("rating", 4), Equals, scopes.ErrReplyClosed)
+}
@@ -0,0 +1,24 @@
+package scopestest_test
+
+import (
+	. "gopkg.in/check.v1"
+	"launchpad.net/go-unityscopes/v2/scopestest"
+)
+
+func (s *S) TestNewResult(c *C) {
+	r, err := scopestest.NewResult(map[string]interface{}{
+		"uri":   "http://example.com",
+		"title": "The title",
+		"count": 2,
+	})
+	c.Assert(err, IsNil)
+	c.Check(r.URI(), Equals, "http://example.com")
+	c.Check(r.Title(), Equals, "The title")
+
+	var count int
+	c.Check(r.Get("count", &count), IsNil)
+	c.Check(count, Equals, 2)
+
+	_, err = scopestest.NewResult(map[string]interface{}{"bad": func() {}})
+	c.Check(err, NotNil)
+}
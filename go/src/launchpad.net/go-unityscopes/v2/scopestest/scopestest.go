@@ -0,0 +1,53 @@
+/*
+Package scopestest provides fake replies for unit testing scopes.
+
+SearchReply and PreviewReply implement scopes.SearchReplier and
+scopes.PreviewReplier, and record everything a scope sends to them.
+Queries, metadata, results and the scope base are the scopes types
+themselves, so a scope's Search and Preview methods can be called
+directly:
+
+    reply := scopestest.NewSearchReply()
+    query := scopes.NewCannedQuery("myscope", "foo", "")
+    metadata := scopes.NewSearchMetadata(20, "en_US", "phone")
+    err := scope.Search(query, metadata, reply, nil)
+
+    // reply.Categories, reply.Results, reply.Filters and
+    // reply.Departments now hold what the scope pushed.
+
+When built with CGO_ENABLED=0, the scopes package keeps those types in
+Go memory instead of the scopes runtime, so tests built on scopestest
+do not need libunity-scopes.
+*/
+package scopestest
+
+import (
+	"launchpad.net/go-unityscopes/v2"
+)
+
+// NewResult creates a result with the given attributes, such as one
+// a scope would be asked to preview or activate.
+func NewResult(attrs map[string]interface{}) (*scopes.Result, error) {
+	result := scopes.NewResult()
+	for name, value := range attrs {
+		if err := result.Set(name, value); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// ChangeSettings replaces the settings held by base, which must have
+// been created with scopes.NewScopeBase, as if the user had changed
+// them.  If the scope implements scopes.SettingsNotifier, it is told
+// about the change as the scopes runtime would before its next
+// request.
+func ChangeSettings(scope scopes.Scope, base *scopes.ScopeBase, value interface{}) error {
+	if err := base.SetSettings(value); err != nil {
+		return err
+	}
+	if notifier, ok := scope.(scopes.SettingsNotifier); ok {
+		notifier.SettingsChanged()
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package scopestest_test
+
+import (
+	. "gopkg.in/check.v1"
+	"launchpad.net/go-unityscopes/v2"
+	"launchpad.net/go-unityscopes/v2/scopestest"
+)
+
+type settingsScope struct {
+	base    *scopes.ScopeBase
+	changes int
+	layout  int64
+}
+
+func (s *settingsScope) SetScopeBase(base *scopes.ScopeBase) {
+	s.base = base
+}
+
+func (s *settingsScope) Search(query *scopes.CannedQuery, metadata *scopes.SearchMetadata, reply scopes.SearchReplier, cancelled <-chan bool) error {
+	return nil
+}
+
+func (s *settingsScope) Preview(result *scopes.Result, metadata *scopes.ActionMetadata, reply scopes.PreviewReplier, cancelled <-chan bool) error {
+	return nil
+}
+
+func (s *settingsScope) SettingsChanged() {
+	var settings struct {
+		Layout int64 `json:"layout"`
+	}
+	if err := s.base.Settings(&settings); err == nil {
+		s.changes++
+		s.layout = settings.Layout
+	}
+}
+
+func (s *S) TestChangeSettings(c *C) {
+	base := scopes.NewScopeBase("/scope", "/cache", "/tmp")
+	c.Check(base.ScopeDirectory(), Equals, "/scope")
+	c.Check(base.CacheDirectory(), Equals, "/cache")
+	c.Check(base.TmpDirectory(), Equals, "/tmp")
+
+	scope := new(settingsScope)
+	scope.SetScopeBase(base)
+
+	var settings map[string]interface{}
+	c.Check(base.Settings(&settings), IsNil)
+	c.Check(settings, DeepEquals, map[string]interface{}{})
+
+	c.Check(scopestest.ChangeSettings(scope, base, map[string]interface{}{"layout": 1}), IsNil)
+	c.Check(scope.changes, Equals, 1)
+	c.Check(scope.layout, Equals, int64(1))
+
+	c.Check(scopestest.ChangeSettings(scope, base, func() {}), NotNil)
+	c.Check(scope.changes, Equals, 1)
+}
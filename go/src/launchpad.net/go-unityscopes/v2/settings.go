@@ -0,0 +1,347 @@
+package scopes
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SettingType identifies the kind of value held by a scope setting.
+type SettingType string
+
+const (
+	SettingList    SettingType = "list"
+	SettingBoolean SettingType = "boolean"
+	SettingString  SettingType = "string"
+	SettingNumber  SettingType = "number"
+)
+
+// Setting describes a single user configurable scope setting.
+//
+// The value of a list setting is the index of the selected entry in
+// DisplayValues.
+type Setting struct {
+	Id            string
+	Type          SettingType
+	DisplayName   string
+	DefaultValue  interface{}
+	DisplayValues []string
+
+	// LocalizedDisplayNames and LocalizedDisplayValues hold
+	// translations of DisplayName and DisplayValues, keyed by
+	// language such as "de" or "pt_BR".
+	LocalizedDisplayNames  map[string]string
+	LocalizedDisplayValues map[string][]string
+}
+
+// NewListSetting creates a setting that lets the user pick one of
+// the given display values.  The default value is an index into
+// displayValues.
+func NewListSetting(id, displayName string, displayValues []string, defaultValue int) *Setting {
+	return &Setting{
+		Id:            id,
+		Type:          SettingList,
+		DisplayName:   displayName,
+		DefaultValue:  defaultValue,
+		DisplayValues: displayValues,
+	}
+}
+
+// NewBooleanSetting creates an on/off setting.
+func NewBooleanSetting(id, displayName string, defaultValue bool) *Setting {
+	return &Setting{
+		Id:           id,
+		Type:         SettingBoolean,
+		DisplayName:  displayName,
+		DefaultValue: defaultValue,
+	}
+}
+
+// NewStringSetting creates a free text setting.
+func NewStringSetting(id, displayName, defaultValue string) *Setting {
+	return &Setting{
+		Id:           id,
+		Type:         SettingString,
+		DisplayName:  displayName,
+		DefaultValue: defaultValue,
+	}
+}
+
+// NewNumberSetting creates a numeric setting.
+func NewNumberSetting(id, displayName string, defaultValue float64) *Setting {
+	return &Setting{
+		Id:           id,
+		Type:         SettingNumber,
+		DisplayName:  displayName,
+		DefaultValue: defaultValue,
+	}
+}
+
+// Localize adds a translation of the setting's display name and, for
+// list settings, its display values.  It returns the setting so that
+// it can be used in a call to NewSettingsDefinition.
+func (s *Setting) Localize(lang, displayName string, displayValues ...string) *Setting {
+	if s.LocalizedDisplayNames == nil {
+		s.LocalizedDisplayNames = make(map[string]string)
+	}
+	s.LocalizedDisplayNames[lang] = displayName
+	if len(displayValues) > 0 {
+		if s.LocalizedDisplayValues == nil {
+			s.LocalizedDisplayValues = make(map[string][]string)
+		}
+		s.LocalizedDisplayValues[lang] = displayValues
+	}
+	return s
+}
+
+// validateLocalizations checks the translations of the setting's
+// display name and values.
+func (s *Setting) validateLocalizations() error {
+	for lang, name := range s.LocalizedDisplayNames {
+		if lang == "" || strings.ContainsAny(lang, "[]\n") {
+			return fmt.Errorf("Setting %q has invalid language %q", s.Id, lang)
+		}
+		if strings.Contains(name, "\n") {
+			return fmt.Errorf("Display name of setting %q must not contain newlines", s.Id)
+		}
+	}
+	for lang, values := range s.LocalizedDisplayValues {
+		if lang == "" || strings.ContainsAny(lang, "[]\n") {
+			return fmt.Errorf("Setting %q has invalid language %q", s.Id, lang)
+		}
+		if s.Type != SettingList {
+			return fmt.Errorf("Setting %q has display values but is not a list setting", s.Id)
+		}
+		if len(values) != len(s.DisplayValues) {
+			return fmt.Errorf("List setting %q has %d display values for %q, expected %d", s.Id, len(values), lang, len(s.DisplayValues))
+		}
+		for _, v := range values {
+			if v == "" || strings.ContainsAny(v, ";\n") {
+				return fmt.Errorf("List setting %q has invalid display value %q", s.Id, v)
+			}
+		}
+	}
+	return nil
+}
+
+// validate checks that the setting is well formed.
+func (s *Setting) validate() error {
+	if s.Id == "" {
+		return errors.New("Setting ID must not be empty")
+	}
+	if strings.ContainsAny(s.Id, "[]\n") {
+		return fmt.Errorf("Setting ID %q contains invalid characters", s.Id)
+	}
+	if strings.Contains(s.DisplayName, "\n") {
+		return fmt.Errorf("Display name of setting %q must not contain newlines", s.Id)
+	}
+	switch s.Type {
+	case SettingList:
+		if len(s.DisplayValues) == 0 {
+			return fmt.Errorf("List setting %q has no display values", s.Id)
+		}
+		for _, v := range s.DisplayValues {
+			if v == "" || strings.ContainsAny(v, ";\n") {
+				return fmt.Errorf("List setting %q has invalid display value %q", s.Id, v)
+			}
+		}
+		index, ok := s.DefaultValue.(int)
+		if !ok {
+			return fmt.Errorf("Default value of list setting %q must be an int", s.Id)
+		}
+		if index < 0 || index >= len(s.DisplayValues) {
+			return fmt.Errorf("Default value of list setting %q is out of range", s.Id)
+		}
+	case SettingBoolean:
+		if _, ok := s.DefaultValue.(bool); !ok {
+			return fmt.Errorf("Default value of boolean setting %q must be a bool", s.Id)
+		}
+	case SettingString:
+		value, ok := s.DefaultValue.(string)
+		if !ok {
+			return fmt.Errorf("Default value of string setting %q must be a string", s.Id)
+		}
+		if strings.Contains(value, "\n") {
+			return fmt.Errorf("Default value of string setting %q must not contain newlines", s.Id)
+		}
+	case SettingNumber:
+		value, ok := s.DefaultValue.(float64)
+		if !ok {
+			return fmt.Errorf("Default value of number setting %q must be a float64", s.Id)
+		}
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return fmt.Errorf("Default value of number setting %q must be finite", s.Id)
+		}
+	default:
+		return fmt.Errorf("Setting %q has unknown type %q", s.Id, s.Type)
+	}
+	return s.validateLocalizations()
+}
+
+// validateValue checks that a value decoded from the scope settings
+// is acceptable for this setting.
+func (s *Setting) validateValue(value interface{}) error {
+	switch s.Type {
+	case SettingList:
+		index, ok := value.(float64)
+		if !ok || index != math.Trunc(index) {
+			return fmt.Errorf("Value of list setting %q must be an integer", s.Id)
+		}
+		if index < 0 || int(index) >= len(s.DisplayValues) {
+			return fmt.Errorf("Value of list setting %q is out of range", s.Id)
+		}
+	case SettingBoolean:
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("Value of boolean setting %q must be a boolean", s.Id)
+		}
+	case SettingString:
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("Value of string setting %q must be a string", s.Id)
+		}
+	case SettingNumber:
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("Value of number setting %q must be a number", s.Id)
+		}
+	}
+	return nil
+}
+
+func (s *Setting) formatDefault() string {
+	switch value := s.DefaultValue.(type) {
+	case int:
+		return strconv.Itoa(value)
+	case bool:
+		return strconv.FormatBool(value)
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	case string:
+		return value
+	}
+	return ""
+}
+
+// SettingsDefinition describes the complete set of settings offered
+// by a scope.
+//
+// A scope can describe its settings in Go by implementing the
+// SettingsDefiner interface.  The definition is then used to validate
+// the values chosen by the user, and to generate the scope's
+// ${scope_name}-settings.ini file.
+type SettingsDefinition struct {
+	Settings []*Setting
+}
+
+// NewSettingsDefinition creates a settings definition holding the
+// given settings, in display order.
+func NewSettingsDefinition(settings ...*Setting) *SettingsDefinition {
+	return &SettingsDefinition{
+		Settings: settings,
+	}
+}
+
+// Setting returns the setting with the given ID, or nil if there is
+// no such setting.
+func (def *SettingsDefinition) Setting(id string) *Setting {
+	for _, s := range def.Settings {
+		if s.Id == id {
+			return s
+		}
+	}
+	return nil
+}
+
+// Validate checks that every setting is well formed and that setting
+// IDs are unique.
+func (def *SettingsDefinition) Validate() error {
+	seen := make(map[string]bool)
+	for _, s := range def.Settings {
+		if err := s.validate(); err != nil {
+			return err
+		}
+		if seen[s.Id] {
+			return fmt.Errorf("Duplicate setting ID %q", s.Id)
+		}
+		seen[s.Id] = true
+	}
+	return nil
+}
+
+// ValidateValues checks a set of setting values, as decoded from the
+// JSON returned by ScopeBase.Settings, against the definition.
+//
+// Values for settings that are not part of the definition are
+// ignored.
+func (def *SettingsDefinition) ValidateValues(values map[string]interface{}) error {
+	for _, s := range def.Settings {
+		value, ok := values[s.Id]
+		if !ok {
+			continue
+		}
+		if err := s.validateValue(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteIni writes the definition in the format expected for the
+// scope's ${scope_name}-settings.ini file.
+func (def *SettingsDefinition) WriteIni(w io.Writer) error {
+	if err := def.Validate(); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for i, s := range def.Settings {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(&buf, "[%s]\n", s.Id)
+		fmt.Fprintf(&buf, "type = %s\n", s.Type)
+		fmt.Fprintf(&buf, "defaultValue = %s\n", s.formatDefault())
+		fmt.Fprintf(&buf, "displayName = %s\n", s.DisplayName)
+		for _, lang := range sortedLanguages(s.LocalizedDisplayNames) {
+			fmt.Fprintf(&buf, "displayName[%s] = %s\n", lang, s.LocalizedDisplayNames[lang])
+		}
+		if s.Type == SettingList {
+			fmt.Fprintf(&buf, "displayValues = %s\n", strings.Join(s.DisplayValues, ";"))
+			for _, lang := range sortedLanguages(s.LocalizedDisplayValues) {
+				fmt.Fprintf(&buf, "displayValues[%s] = %s\n", lang, strings.Join(s.LocalizedDisplayValues[lang], ";"))
+			}
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// sortedLanguages returns the keys of a map of translations in order,
+// so that the generated ini file is stable.
+func sortedLanguages(translations interface{}) []string {
+	var langs []string
+	switch t := translations.(type) {
+	case map[string]string:
+		for lang := range t {
+			langs = append(langs, lang)
+		}
+	case map[string][]string:
+		for lang := range t {
+			langs = append(langs, lang)
+		}
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// WriteIniFile writes the definition to the named file.
+func (def *SettingsDefinition) WriteIniFile(filename string) error {
+	var buf bytes.Buffer
+	if err := def.WriteIni(&buf); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, buf.Bytes(), 0644)
+}
@@ -0,0 +1,151 @@
+package scopes_test
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+	"launchpad.net/go-unityscopes/v2"
+)
+
+func (s *S) TestSettingsWriteIni(c *C) {
+	def := scopes.NewSettingsDefinition(
+		scopes.NewListSetting("layout", "Layout", []string{"Grid", "List"}, 1),
+		scopes.NewBooleanSetting("explicit", "Show explicit results", false),
+		scopes.NewStringSetting("location", "Location", "London"),
+		scopes.NewNumberSetting("distance", "Distance", 2.5),
+	)
+	c.Check(def.Validate(), IsNil)
+
+	var buf bytes.Buffer
+	c.Assert(def.WriteIni(&buf), IsNil)
+	c.Check(buf.String(), Equals, `[layout]
+type = list
+defaultValue = 1
+displayName = Layout
+displayValues = Grid;List
+
+[explicit]
+type = boolean
+defaultValue = false
+displayName = Show explicit results
+
+[location]
+type = string
+defaultValue = London
+displayName = Location
+
+[distance]
+type = number
+defaultValue = 2.5
+displayName = Distance
+`)
+}
+
+func (s *S) TestSettingsWriteIniLocalized(c *C) {
+	def := scopes.NewSettingsDefinition(
+		scopes.NewListSetting("layout", "Layout", []string{"Grid", "List"}, 0).
+			Localize("fr", "Disposition", "Grille", "Liste").
+			Localize("de", "Anordnung", "Raster", "Liste"),
+		scopes.NewBooleanSetting("explicit", "Show explicit results", false).
+			Localize("de", "Explizite Ergebnisse anzeigen"),
+	)
+
+	var buf bytes.Buffer
+	c.Assert(def.WriteIni(&buf), IsNil)
+	c.Check(buf.String(), Equals, `[layout]
+type = list
+defaultValue = 0
+displayName = Layout
+displayName[de] = Anordnung
+displayName[fr] = Disposition
+displayValues = Grid;List
+displayValues[de] = Raster;Liste
+displayValues[fr] = Grille;Liste
+
+[explicit]
+type = boolean
+defaultValue = false
+displayName = Show explicit results
+displayName[de] = Explizite Ergebnisse anzeigen
+`)
+
+	def = scopes.NewSettingsDefinition(
+		scopes.NewListSetting("layout", "Layout", []string{"Grid", "List"}, 0).
+			Localize("de", "Anordnung", "Raster"),
+	)
+	c.Check(def.Validate(), ErrorMatches, `List setting "layout" has 1 display values for "de", expected 2`)
+
+	def = scopes.NewSettingsDefinition(
+		scopes.NewBooleanSetting("explicit", "Show explicit results", false).
+			Localize("de", "Explizite Ergebnisse anzeigen", "Ja"),
+	)
+	c.Check(def.Validate(), ErrorMatches, `Setting "explicit" has display values but is not a list setting`)
+}
+
+func (s *S) TestSettingsLookup(c *C) {
+	layout := scopes.NewListSetting("layout", "Layout", []string{"Grid", "List"}, 0)
+	def := scopes.NewSettingsDefinition(layout)
+	c.Check(def.Setting("layout"), Equals, layout)
+	c.Check(def.Setting("missing"), IsNil)
+}
+
+func (s *S) TestSettingsValidate(c *C) {
+	def := scopes.NewSettingsDefinition(
+		scopes.NewListSetting("layout", "Layout", []string{"Grid", "List"}, 2),
+	)
+	c.Check(def.Validate(), ErrorMatches, `Default value of list setting "layout" is out of range`)
+
+	def = scopes.NewSettingsDefinition(
+		scopes.NewListSetting("layout", "Layout", []string{"Grid;Cards"}, 0),
+	)
+	c.Check(def.Validate(), ErrorMatches, `List setting "layout" has invalid display value "Grid;Cards"`)
+
+	def = scopes.NewSettingsDefinition(
+		scopes.NewBooleanSetting("flag", "Flag", true),
+		scopes.NewBooleanSetting("flag", "Other flag", false),
+	)
+	c.Check(def.Validate(), ErrorMatches, `Duplicate setting ID "flag"`)
+
+	def = scopes.NewSettingsDefinition(&scopes.Setting{
+		Id:           "distance",
+		Type:         scopes.SettingNumber,
+		DisplayName:  "Distance",
+		DefaultValue: 3,
+	})
+	c.Check(def.Validate(), ErrorMatches, `Default value of number setting "distance" must be a float64`)
+
+	def = scopes.NewSettingsDefinition(&scopes.Setting{
+		Id:   "colour",
+		Type: "colour",
+	})
+	c.Check(def.Validate(), ErrorMatches, `Setting "colour" has unknown type "colour"`)
+
+	// WriteIni refuses to write an invalid definition
+	var buf bytes.Buffer
+	c.Check(def.WriteIni(&buf), NotNil)
+	c.Check(buf.Len(), Equals, 0)
+}
+
+func (s *S) TestSettingsValidateValues(c *C) {
+	def := scopes.NewSettingsDefinition(
+		scopes.NewListSetting("layout", "Layout", []string{"Grid", "List"}, 0),
+		scopes.NewBooleanSetting("explicit", "Show explicit results", false),
+		scopes.NewStringSetting("location", "Location", ""),
+		scopes.NewNumberSetting("distance", "Distance", 1),
+	)
+
+	c.Check(def.ValidateValues(map[string]interface{}{
+		"layout":   1.0,
+		"explicit": true,
+		"location": "Paris",
+		"distance": 10.0,
+		"unknown":  "ignored",
+	}), IsNil)
+	c.Check(def.ValidateValues(map[string]interface{}{}), IsNil)
+
+	c.Check(def.ValidateValues(map[string]interface{}{"layout": 2.0}), ErrorMatches, `Value of list setting "layout" is out of range`)
+	c.Check(def.ValidateValues(map[string]interface{}{"layout": 0.5}), ErrorMatches, `Value of list setting "layout" must be an integer`)
+	c.Check(def.ValidateValues(map[string]interface{}{"explicit": "yes"}), ErrorMatches, `Value of boolean setting "explicit" must be a boolean`)
+	c.Check(def.ValidateValues(map[string]interface{}{"location": 42.0}), ErrorMatches, `Value of string setting "location" must be a string`)
+	c.Check(def.ValidateValues(map[string]interface{}{"distance": "far"}), ErrorMatches, `Value of number setting "distance" must be a number`)
+}
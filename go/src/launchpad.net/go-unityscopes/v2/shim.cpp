@@ -1,5 +1,9 @@
+// +build cgo,!purego
+
 #include <cstring>
+#include <stdexcept>
 
+#include <unity/Exception.h>
 #include <unity/scopes/Category.h>
 #include <unity/scopes/Runtime.h>
 
@@ -12,6 +16,33 @@ extern "C" {
 #include "scope.h"
 
 using namespace unity::scopes;
+
+namespace gounityscopes {
+namespace internal {
+
+// Returns the exception message prefixed by the exception's class
+// name and a unit separator, so the Go side can map it to a typed
+// error.  The string must be freed by the caller.
+char *exception_string(const std::exception &e) {
+    std::string name;
+    if (auto unity_exception = dynamic_cast<const unity::Exception*>(&e)) {
+        name = unity_exception->name();
+    } else if (dynamic_cast<const std::invalid_argument*>(&e)) {
+        name = "std::invalid_argument";
+    } else if (dynamic_cast<const std::out_of_range*>(&e)) {
+        name = "std::out_of_range";
+    } else if (dynamic_cast<const std::logic_error*>(&e)) {
+        name = "std::logic_error";
+    } else if (dynamic_cast<const std::runtime_error*>(&e)) {
+        name = "std::runtime_error";
+    } else {
+        name = "std::exception";
+    }
+    return strdup((name + '\x1f' + e.what()).c_str());
+}
+
+}
+}
 using namespace gounityscopes::internal;
 
 void run_scope(void *scope_name, void *runtime_config, void *scope_config,
@@ -22,7 +53,7 @@ void run_scope(void *scope_name, void *runtime_config, void *scope_config,
         ScopeAdapter scope(*reinterpret_cast<GoInterface*>(pointer_to_iface));
         runtime->run_scope(&scope, from_gostring(scope_config));
     } catch (const std::exception &e) {
-        *error = strdup(e.what());
+        *error = exception_string(e);
     }
 }
 
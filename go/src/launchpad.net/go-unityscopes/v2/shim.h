@@ -43,7 +43,8 @@ void search_reply_finished(SharedPtrData reply);
 void search_reply_error(SharedPtrData reply, void *err_string);
 void search_reply_register_category(SharedPtrData reply, void *id, void *title, void *icon, void *cat_template, SharedPtrData category);
 void search_reply_register_departments(SharedPtrData reply, SharedPtrData dept);
-void search_reply_push(SharedPtrData reply, _CategorisedResult *result, char **error);
+int search_reply_push(SharedPtrData reply, _CategorisedResult *result, char **error);
+int search_reply_push_all(SharedPtrData reply, SharedPtrData *categories, void *results_json, int count, char **error);
 void search_reply_push_filters(SharedPtrData reply, void *filters_json, void *filter_state_json, char **error);
 
 /* PreviewReply objects */
@@ -76,6 +77,7 @@ void destroy_result(_Result *res);
 
 /* Result objects */
 void *result_get_attr(_Result *res, void *attr, int *length, char **error);
+void *result_get_attrs(_Result *res, int *length, char **error);
 void result_set_attr(_Result *res, void *attr, void *json_value, char **error);
 void result_set_intercept_activation(_Result *res);
 
@@ -140,6 +142,8 @@ void *column_layout_column(_ColumnLayout *layout, int column, int *n_items, char
 
 /* Helpers for tests */
 _Result *new_testing_result(void);
+void new_testing_category(void *id, SharedPtrData category);
+void testing_build_results(SharedPtrData *categories, void *results_json, int count, char **error);
 
 
 #ifdef __cplusplus
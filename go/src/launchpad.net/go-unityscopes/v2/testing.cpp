@@ -1,12 +1,16 @@
+// +build cgo,!purego
+
 #include <stdexcept>
 #include <cstring>
 
+#include <unity/scopes/testing/Category.h>
 #include <unity/scopes/testing/Result.h>
 
 extern "C" {
 #include "_cgo_export.h"
 }
 #include "helpers.h"
+#include "smartptr_helper.h"
 
 using namespace unity::scopes;
 using namespace gounityscopes::internal;
@@ -14,3 +18,17 @@ using namespace gounityscopes::internal;
 _Result *new_testing_result() {
     return reinterpret_cast<_Result*>(static_cast<Result*>(new testing::Result));
 }
+
+void new_testing_category(void *id, SharedPtrData category) {
+    std::string cat_id = from_gostring(id);
+    Category::SCPtr cat(new testing::Category(cat_id, cat_id, "", CategoryRenderer()));
+    init_ptr<const Category>(category, cat);
+}
+
+void testing_build_results(SharedPtrData *categories, void *results_json, int count, char **error) {
+    try {
+        build_results(categories, results_json, count);
+    } catch (const std::exception &e) {
+        *error = exception_string(e);
+    }
+}
@@ -1,11 +1,43 @@
+// +build cgo,!purego
+
 package scopes
 
 // #include "shim.h"
 import "C"
+import (
+	"runtime"
+	"unsafe"
+)
 
-// These functions are used by tests.  They are not part of a
-// *_test.go file because they make use of cgo.
+// These functions are used by tests and by Replay.  They are not
+// part of a *_test.go file because they make use of cgo.
 
 func newTestingResult() *Result {
 	return makeResult(C.new_testing_result())
 }
+
+// newTestingCategory creates a category backed by the scopes
+// runtime without registering it with a SearchReply.
+func newTestingCategory(id string) *Category {
+	cat := NewCategory(id, id, "", "")
+	runtime.SetFinalizer(cat, finalizeCategory)
+	C.new_testing_category(unsafe.Pointer(&id), &cat.c[0])
+	return cat
+}
+
+// buildTestingResults builds a batch of results in the scopes runtime
+// as SearchReply.PushAll would, without pushing them anywhere.
+func buildTestingResults(results []ResultSetter) error {
+	categories, data, err := encodeResults(results)
+	if err != nil || len(categories) == 0 {
+		return err
+	}
+	cats := make([]C.SharedPtrData, len(categories))
+	for i, cat := range categories {
+		cats[i] = cat.c
+	}
+	var errorString *C.char
+	C.testing_build_results(&cats[0], unsafe.Pointer(&data), C.int(len(cats)), &errorString)
+	runtime.KeepAlive(categories)
+	return checkError(errorString)
+}
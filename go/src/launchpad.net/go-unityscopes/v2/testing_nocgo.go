@@ -0,0 +1,25 @@
+// +build !cgo purego
+
+package scopes
+
+// These functions are used by tests and by Replay, in place of the
+// runtime backed versions in testing.go.
+
+func newTestingResult() *Result {
+	return new(Result)
+}
+
+// newTestingCategory creates a category that can be used for results
+// without registering it with a SearchReply.
+func newTestingCategory(id string) *Category {
+	cat := NewCategory(id, id, "", "")
+	cat.registered = true
+	return cat
+}
+
+// buildTestingResults checks that a batch of results can be encoded
+// as SearchReply.PushAll would, without pushing them anywhere.
+func buildTestingResults(results []ResultSetter) error {
+	_, _, err := encodeResults(results)
+	return err
+}
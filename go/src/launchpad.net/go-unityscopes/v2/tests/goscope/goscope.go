@@ -27,7 +27,7 @@ type MyScope struct {
 	base *scopes.ScopeBase
 }
 
-func (s *MyScope) Preview(result *scopes.Result, metadata *scopes.ActionMetadata, reply *scopes.PreviewReply, cancelled <-chan bool) error {
+func (s *MyScope) Preview(result *scopes.Result, metadata *scopes.ActionMetadata, reply scopes.PreviewReplier, cancelled <-chan bool) error {
 	layout1col := scopes.NewColumnLayout(1)
 	layout2col := scopes.NewColumnLayout(2)
 	layout3col := scopes.NewColumnLayout(3)
@@ -93,7 +93,7 @@ func (s *MyScope) Preview(result *scopes.Result, metadata *scopes.ActionMetadata
 	return nil
 }
 
-func (s *MyScope) Search(query *scopes.CannedQuery, metadata *scopes.SearchMetadata, reply *scopes.SearchReply, cancelled <-chan bool) error {
+func (s *MyScope) Search(query *scopes.CannedQuery, metadata *scopes.SearchMetadata, reply scopes.SearchReplier, cancelled <-chan bool) error {
 	root_department := s.CreateDepartments(query, metadata, reply)
 	reply.RegisterDepartments(root_department)
 
@@ -123,10 +123,10 @@ func (s *MyScope) SetScopeBase(base *scopes.ScopeBase) {
 
 // RESULTS *********************************************************************
 
-func (s *MyScope) AddQueryResults(reply *scopes.SearchReply, query string) error {
+func (s *MyScope) AddQueryResults(reply scopes.SearchReplier, query string) error {
 	cat := reply.RegisterCategory("category", "Category", "", searchCategoryTemplate)
 
-	result := scopes.NewCategorisedResult(cat)
+	result := reply.NewResult(cat)
 	result.SetURI("http://localhost/" + query)
 	result.SetDndURI("http://localhost_dnduri" + query)
 	result.SetTitle("TEST" + query)
@@ -180,7 +180,7 @@ func SearchDepartment(root *scopes.Department, id string) *scopes.Department {
 
 func (s *MyScope) GetRockSubdepartments(query *scopes.CannedQuery,
 	metadata *scopes.SearchMetadata,
-	reply *scopes.SearchReply) *scopes.Department {
+	reply scopes.SearchReplier) *scopes.Department {
 	active_dep, err := scopes.NewDepartment("Rock", query, "Rock Music")
 	if err == nil {
 		active_dep.SetAlternateLabel("Rock Music Alt")
@@ -196,7 +196,7 @@ func (s *MyScope) GetRockSubdepartments(query *scopes.CannedQuery,
 
 func (s *MyScope) GetSoulSubdepartments(query *scopes.CannedQuery,
 	metadata *scopes.SearchMetadata,
-	reply *scopes.SearchReply) *scopes.Department {
+	reply scopes.SearchReplier) *scopes.Department {
 	active_dep, err := scopes.NewDepartment("Soul", query, "Soul Music")
 	if err == nil {
 		active_dep.SetAlternateLabel("Soul Music Alt")
@@ -212,7 +212,7 @@ func (s *MyScope) GetSoulSubdepartments(query *scopes.CannedQuery,
 
 func (s *MyScope) CreateDepartments(query *scopes.CannedQuery,
 	metadata *scopes.SearchMetadata,
-	reply *scopes.SearchReply) *scopes.Department {
+	reply scopes.SearchReplier) *scopes.Department {
 	department, _ := scopes.NewDepartment("", query, "Browse Music")
 	department.SetAlternateLabel("Browse Music Alt")
 
@@ -1,3 +1,5 @@
+// +build cgo,!purego
+
 package scopes
 
 /*
@@ -8,9 +10,11 @@ package scopes
 */
 import "C"
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"flag"
+	"log"
 	"path"
 	"strings"
 	"sync"
@@ -19,7 +23,7 @@ import (
 
 func checkError(errorString *C.char) (err error) {
 	if errorString != nil {
-		err = errors.New(C.GoString(errorString))
+		err = parseError(C.GoString(errorString))
 		C.free(unsafe.Pointer(errorString))
 	}
 	return
@@ -27,42 +31,45 @@ func checkError(errorString *C.char) (err error) {
 
 // Category represents a search result category.
 type Category struct {
-	c C.SharedPtrData
+	c        C.SharedPtrData
+	id       string
+	title    string
+	icon     string
+	template string
 }
 
 func finalizeCategory(cat *Category) {
 	C.destroy_category_ptr(&cat.c[0])
 }
 
-// Scope defines the interface that scope implementations must implement
-type Scope interface {
-	SetScopeBase(base *ScopeBase)
-	Search(query *CannedQuery, metadata *SearchMetadata, reply *SearchReply, cancelled <-chan bool) error
-	Preview(result *Result, metadata *ActionMetadata, reply *PreviewReply, cancelled <-chan bool) error
+func (cat *Category) isRegistered() bool {
+	return cat.c[0] != 0 || cat.c[1] != 0
 }
 
-// Activator is an interface that should be implemented by scopes that
-// need to handle result activation directly.
-type Activator interface {
+// runtimeScope is the scope as held by the C++ side.  cgo can only
+// export functions taking interface types declared in files that
+// import "C", so Scope itself can not be used.
+type runtimeScope interface {
 	Scope
-	Activate(result *Result, metadata *ActionMetadata) (*ActivationResponse, error)
-}
-
-// PerformActioner is an interface that should be implemented by
-// scopes that need to handle preview actions directly.
-type PerformActioner interface {
-	Scope
-	PerformAction(result *Result, metadata *ActionMetadata, widgetId, actionId string) (*ActivationResponse, error)
 }
 
 //export callScopeSearch
-func callScopeSearch(scope Scope, queryPtr, metadataPtr unsafe.Pointer, replyData *C.uintptr_t, cancel <-chan bool) {
+func callScopeSearch(scope runtimeScope, queryPtr, metadataPtr unsafe.Pointer, replyData *C.uintptr_t, cancel <-chan bool) {
+	checkSettingsChanged(scope)
 	query := makeCannedQuery((*C._CannedQuery)(queryPtr))
 	metadata := makeSearchMetadata((*C._SearchMetadata)(metadataPtr))
 	reply := makeSearchReply(replyData)
+	ctx, cancelled, done := newQueryContext(cancel, *queryTimeout, func() {
+		log.Printf("Search exceeded the query timeout of %v, finishing reply early", *queryTimeout)
+		reply.Finished()
+	})
+	metadata.ctx = ctx
 
 	go func() {
-		err := scope.Search(query, metadata, reply, cancel)
+		defer done()
+		err := callRecovered("Search", func() error {
+			return scope.Search(query, metadata, reply, cancelled)
+		})
 		if err != nil {
 			reply.Error(err)
 			return
@@ -72,13 +79,22 @@ func callScopeSearch(scope Scope, queryPtr, metadataPtr unsafe.Pointer, replyDat
 }
 
 //export callScopePreview
-func callScopePreview(scope Scope, resultPtr, metadataPtr unsafe.Pointer, replyData *C.uintptr_t, cancel <-chan bool) {
+func callScopePreview(scope runtimeScope, resultPtr, metadataPtr unsafe.Pointer, replyData *C.uintptr_t, cancel <-chan bool) {
+	checkSettingsChanged(scope)
 	result := makeResult((*C._Result)(resultPtr))
 	metadata := makeActionMetadata((*C._ActionMetadata)(metadataPtr))
 	reply := makePreviewReply(replyData)
+	ctx, cancelled, done := newQueryContext(cancel, *queryTimeout, func() {
+		log.Printf("Preview exceeded the query timeout of %v, finishing reply early", *queryTimeout)
+		reply.Finished()
+	})
+	metadata.ctx = ctx
 
 	go func() {
-		err := scope.Preview(result, metadata, reply, cancel)
+		defer done()
+		err := callRecovered("Preview", func() error {
+			return scope.Preview(result, metadata, reply, cancelled)
+		})
 		if err != nil {
 			reply.Error(err)
 			return
@@ -88,12 +104,17 @@ func callScopePreview(scope Scope, resultPtr, metadataPtr unsafe.Pointer, replyD
 }
 
 //export callScopeActivate
-func callScopeActivate(scope Scope, resultPtr, metadataPtr, responsePtr unsafe.Pointer, errorPtr **C.char) {
+func callScopeActivate(scope runtimeScope, resultPtr, metadataPtr, responsePtr unsafe.Pointer, errorPtr **C.char) {
+	checkSettingsChanged(scope)
 	switch s := scope.(type) {
 	case Activator:
 		result := makeResult((*C._Result)(resultPtr))
 		metadata := makeActionMetadata((*C._ActionMetadata)(metadataPtr))
-		response, err := s.Activate(result, metadata)
+		var response *ActivationResponse
+		err := callRecovered("Activate", func() (err error) {
+			response, err = s.Activate(result, metadata)
+			return
+		})
 		if err == nil {
 			err = response.update((*C._ActivationResponse)(responsePtr))
 		}
@@ -106,12 +127,17 @@ func callScopeActivate(scope Scope, resultPtr, metadataPtr, responsePtr unsafe.P
 }
 
 //export callScopePerformAction
-func callScopePerformAction(scope Scope, resultPtr, metadataPtr unsafe.Pointer, widgetId, actionId *C.char, responsePtr unsafe.Pointer, errorPtr **C.char) {
+func callScopePerformAction(scope runtimeScope, resultPtr, metadataPtr unsafe.Pointer, widgetId, actionId *C.char, responsePtr unsafe.Pointer, errorPtr **C.char) {
+	checkSettingsChanged(scope)
 	switch s := scope.(type) {
 	case PerformActioner:
 		result := makeResult((*C._Result)(resultPtr))
 		metadata := makeActionMetadata((*C._ActionMetadata)(metadataPtr))
-		response, err := s.PerformAction(result, metadata, C.GoString(widgetId), C.GoString(actionId))
+		var response *ActivationResponse
+		err := callRecovered("PerformAction", func() (err error) {
+			response, err = s.PerformAction(result, metadata, C.GoString(widgetId), C.GoString(actionId))
+			return
+		})
 		if err == nil {
 			err = response.update((*C._ActivationResponse)(responsePtr))
 		}
@@ -123,28 +149,90 @@ func callScopePerformAction(scope Scope, resultPtr, metadataPtr unsafe.Pointer,
 	}
 }
 
+func (r *ActivationResponse) update(responsePtr *C._ActivationResponse) error {
+	if r.Status == ActivationPerformQuery {
+		C.activation_response_init_query(responsePtr, r.Query.q)
+	} else {
+		C.activation_response_init_status(responsePtr, C.int(r.Status))
+	}
+	if r.ScopeData != nil {
+		data, err := json.Marshal(r.ScopeData)
+		if err != nil {
+			return serializationError(err)
+		}
+		var errorString *C.char
+		C.activation_response_set_scope_data(responsePtr, (*C.char)(unsafe.Pointer(&data[0])), C.int(len(data)), &errorString)
+		if err = checkError(errorString); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 var (
-	runtimeConfig = flag.String("runtime", "", "The runtime configuration file for the Unity Scopes library")
-	scopeConfig   = flag.String("scope", "", "The scope configuration file for the Unity Scopes library")
+	scopeBases     = make(map[Scope]*ScopeBase)
+	scopeBasesLock sync.Mutex
 )
 
-// ScopeBase exposes information about the scope including settings
-// and various directories available for use.
-type ScopeBase struct {
-	b unsafe.Pointer
-}
-
 //export setScopeBase
-func setScopeBase(scope Scope, b unsafe.Pointer) {
+func setScopeBase(scope runtimeScope, b unsafe.Pointer) {
 	if b == nil {
+		scopeBasesLock.Lock()
+		delete(scopeBases, scope)
+		scopeBasesLock.Unlock()
 		scope.SetScopeBase(nil)
 	} else {
-		scope.SetScopeBase(&ScopeBase{b})
+		base := &ScopeBase{b: b}
+		if _, ok := scope.(SettingsNotifier); ok {
+			base.settings = base.settingsData()
+			base.cached = true
+		}
+		scopeBasesLock.Lock()
+		scopeBases[scope] = base
+		scopeBasesLock.Unlock()
+		scope.SetScopeBase(base)
 	}
 }
 
+// checkSettingsChanged polls the scope's settings, and notifies the
+// scope if they have changed since the last poll.
+func checkSettingsChanged(scope Scope) {
+	notifier, ok := scope.(SettingsNotifier)
+	if !ok {
+		return
+	}
+	scopeBasesLock.Lock()
+	base := scopeBases[scope]
+	scopeBasesLock.Unlock()
+	if base == nil {
+		return
+	}
+
+	data := base.settingsData()
+	base.settingsLock.Lock()
+	changed := !bytes.Equal(data, base.settings)
+	base.settings = data
+	base.settingsLock.Unlock()
+	if !changed {
+		return
+	}
+
+	if definition := settingsDefinition(scope); definition != nil {
+		var values map[string]interface{}
+		if err := json.Unmarshal(data, &values); err != nil {
+			log.Println("Could not decode scope settings:", err)
+		} else if err := definition.ValidateValues(values); err != nil {
+			log.Println("Invalid scope settings:", err)
+		}
+	}
+	notifier.SettingsChanged()
+}
+
 // ScopeDirectory returns the directory where the scope has been installed
 func (b *ScopeBase) ScopeDirectory() string {
+	if b.b == nil {
+		return b.scopeDir
+	}
 	dir := C.scope_base_scope_directory(b.b)
 	defer C.free(unsafe.Pointer(dir))
 	return C.GoString(dir)
@@ -152,6 +240,9 @@ func (b *ScopeBase) ScopeDirectory() string {
 
 // CacheDirectory returns a directory the scope can use to store cache files
 func (b *ScopeBase) CacheDirectory() string {
+	if b.b == nil {
+		return b.cacheDir
+	}
 	dir := C.scope_base_cache_directory(b.b)
 	defer C.free(unsafe.Pointer(dir))
 	return C.GoString(dir)
@@ -159,6 +250,9 @@ func (b *ScopeBase) CacheDirectory() string {
 
 // TmpDirectory returns a directory the scope can use to store temporary files
 func (b *ScopeBase) TmpDirectory() string {
+	if b.b == nil {
+		return b.tmpDir
+	}
 	dir := C.scope_base_tmp_directory(b.b)
 	defer C.free(unsafe.Pointer(dir))
 	return C.GoString(dir)
@@ -166,6 +260,11 @@ func (b *ScopeBase) TmpDirectory() string {
 
 // ListRegistryScopes lists all the scopes existing in the registry
 func (b *ScopeBase) ListRegistryScopes() map[string]*ScopeMetadata {
+	var scopesList = make(map[string]*ScopeMetadata)
+	if b.b == nil {
+		return scopesList
+	}
+
 	var nb_scopes C.int
 	var c_array **C._ScopeMetadata = C.list_registry_scopes_metadata(b.b, &nb_scopes)
 	defer C.free(unsafe.Pointer(c_array))
@@ -173,7 +272,6 @@ func (b *ScopeBase) ListRegistryScopes() map[string]*ScopeMetadata {
 	length := int(nb_scopes)
 	// create a very big slice and then slice it to the number of scopes metadata
 	slice := (*[1 << 27]*C._ScopeMetadata)(unsafe.Pointer(c_array))[:length:length]
-	var scopesList = make(map[string]*ScopeMetadata)
 
 	for i := 0; i < length; i++ {
 		json_data := C.get_scope_metadata_serialized(slice[i])
@@ -186,14 +284,11 @@ func (b *ScopeBase) ListRegistryScopes() map[string]*ScopeMetadata {
 	return scopesList
 }
 
-// Settings returns the scope's settings.  The settings will be
-// decoded into the given value according to the same rules used by
-// json.Unmarshal().
-func (b *ScopeBase) Settings(value interface{}) error {
+func (b *ScopeBase) settingsData() []byte {
 	var length C.int
 	data := C.scope_base_settings(b.b, &length)
 	defer C.free(data)
-	return json.Unmarshal(C.GoBytes(data, length), value)
+	return C.GoBytes(data, length)
 }
 
 /*
@@ -205,6 +300,14 @@ func Run(scope Scope) error {
 	if !flag.Parsed() {
 		flag.Parse()
 	}
+	if *settingsFile != "" {
+		return writeSettings(scope)
+	}
+	scope, closeRecording, err := recordingScope(scope)
+	if err != nil {
+		return err
+	}
+	defer closeRecording()
 	if *scopeConfig == "" {
 		return errors.New("Scope configuration file not set on command line")
 	}
@@ -214,8 +317,9 @@ func Run(scope Scope) error {
 	}
 	scopeId := base[:len(base)-len(".ini")]
 
+	var goscope runtimeScope = scope
 	var errorString *C.char
-	C.run_scope(unsafe.Pointer(&scopeId), unsafe.Pointer(runtimeConfig), unsafe.Pointer(scopeConfig), unsafe.Pointer(&scope), &errorString)
+	C.run_scope(unsafe.Pointer(&scopeId), unsafe.Pointer(runtimeConfig), unsafe.Pointer(scopeConfig), unsafe.Pointer(&goscope), &errorString)
 	return checkError(errorString)
 }
 
@@ -0,0 +1,73 @@
+// +build !cgo purego
+
+package scopes
+
+import (
+	"flag"
+)
+
+// Without cgo there is no scopes runtime, so the types in this file
+// and the other *_nocgo.go files keep their data in Go memory.  They
+// let scopes be built and tested with CGO_ENABLED=0, and behave like
+// their runtime backed counterparts as far as is practical.
+
+// Category represents a search result category.
+type Category struct {
+	// registered is set for the categories made by
+	// newTestingCategory, as there is no SearchReply to register
+	// them with.
+	registered bool
+	id         string
+	title      string
+	icon       string
+	template   string
+}
+
+func (cat *Category) isRegistered() bool {
+	return cat.registered
+}
+
+// ScopeDirectory returns the directory where the scope has been installed
+func (b *ScopeBase) ScopeDirectory() string {
+	return b.scopeDir
+}
+
+// CacheDirectory returns a directory the scope can use to store cache files
+func (b *ScopeBase) CacheDirectory() string {
+	return b.cacheDir
+}
+
+// TmpDirectory returns a directory the scope can use to store temporary files
+func (b *ScopeBase) TmpDirectory() string {
+	return b.tmpDir
+}
+
+// ListRegistryScopes lists all the scopes existing in the registry.
+//
+// Without the scopes runtime there is no registry, so the list is
+// always empty.
+func (b *ScopeBase) ListRegistryScopes() map[string]*ScopeMetadata {
+	return make(map[string]*ScopeMetadata)
+}
+
+// settingsData returns empty settings, as only a base created with
+// NewScopeBase can exist without the runtime, and it holds its
+// settings itself.
+func (b *ScopeBase) settingsData() []byte {
+	return []byte("{}")
+}
+
+/*
+Run writes the scope's settings definition when the --write-settings
+flag is given.  Otherwise it returns ErrNoRuntime, as the scopes
+runtime can only be used when the package is built with cgo.
+*/
+func Run(scope Scope) error {
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+	if *settingsFile != "" {
+		return writeSettings(scope)
+	}
+	return ErrNoRuntime
+}
@@ -8,16 +8,30 @@ var sdk = 'ubuntu-sdk-15.04';
 var paths = {
     src: {
         click: ['click/manifest.json', 'click/falcon.apparmor'],
-        scope: ['images/icon.png', 'images/logo.png', 'src/falcon.bhdouglass_falcon.ini', 'src/falcon.bhdouglass_falcon-settings.ini'],
-        go: 'src/*.go',
+        scope: ['images/icon.png', 'images/logo.png', 'src/falcon.bhdouglass_falcon-settings.ini'],
+        ini: 'src/falcon.bhdouglass_falcon.ini.in',
+        go: './src',
+        po: 'po',
     },
     dist: {
         click: 'dist',
         scope: 'dist/falcon/',
         go: 'dist/falcon/falcon.bhdouglass_falcon',
+        cli: 'dist/falcon-cli',
+        ini: 'dist/falcon/falcon.bhdouglass_falcon.ini',
+        locale: 'dist/share/locale',
     }
 };
 
+//The gettext domain of Falcon's strings, must match textDomain in src/i18n.go
+var domain = 'falcon.bhdouglass';
+
+function findPo() {
+    return fs.readdirSync(paths.src.po).filter(function(file) {
+        return path.extname(file) == '.po';
+    });
+}
+
 gulp.task('clean', function() {
     del.sync(paths.dist.click);
 });
@@ -32,9 +46,38 @@ gulp.task('move-scope', function() {
         .pipe(gulp.dest(paths.dist.scope));
 });
 
-gulp.task('build-go', ['clean', 'move-click', 'move-scope'], shell.task('GOPATH=`pwd`/go go build -o ' + paths.dist.go + ' ' + paths.src.go));
+//Extract the translatable strings from the tr calls in the go code and the _ keys in the scope ini
+gulp.task('pot', shell.task([
+    'intltool-extract --type=gettext/ini ' + paths.src.ini,
+    'xgettext --from-code=UTF-8 --language=C --keyword=tr:2 --keyword=N_ --add-comments=TRANSLATORS ' +
+        '--package-name=' + domain + ' -o ' + paths.src.po + '/' + domain + '.pot ' +
+        paths.src.go + '/*.go ' + paths.src.ini + '.h',
+    'rm ' + paths.src.ini + '.h',
+]));
+
+gulp.task('mo', ['clean'], shell.task(findPo().map(function(file) {
+    var dir = paths.dist.locale + '/' + path.basename(file, '.po') + '/LC_MESSAGES';
+    return 'mkdir -p ' + dir + ' && msgfmt -o ' + dir + '/' + domain + '.mo ' + paths.src.po + '/' + file;
+})));
+
+gulp.task('ini', ['clean'], shell.task([
+    'mkdir -p ' + paths.dist.scope,
+    'intltool-merge -d -u ' + paths.src.po + ' ' + paths.src.ini + ' ' + paths.dist.ini,
+]));
+
+gulp.task('build-go', ['clean', 'move-click', 'move-scope', 'mo', 'ini'], shell.task('GOPATH=`pwd`/go go build -o ' + paths.dist.go + ' ' + paths.src.go));
 
-gulp.task('build-go-armhf', ['clean', 'move-click', 'move-scope'], shell.task(
+gulp.task('build-cli', shell.task('CGO_ENABLED=0 GOPATH=`pwd`/go go build -tags cli -o ' + paths.dist.cli + ' ' + paths.src.go));
+
+gulp.task('test', shell.task('GOPATH=`pwd`/go go test -race ' + paths.src.go));
+
+//The purego tag builds the scopes package without libunity-scopes but keeps cgo, which the race detector needs
+gulp.task('test-race', shell.task('GOPATH=`pwd`/go go test -race -tags purego ' + paths.src.go + ' launchpad.net/go-unityscopes/v2/...'));
+
+//Checks the scope builds and runs without cgo or the scopes runtime
+gulp.task('test-nocgo', shell.task('CGO_ENABLED=0 GOPATH=`pwd`/go go test ' + paths.src.go + ' launchpad.net/go-unityscopes/v2/scopestest'));
+
+gulp.task('build-go-armhf', ['clean', 'move-click', 'move-scope', 'mo', 'ini'], shell.task(
     'CGO_ENABLED=1 ' +
     'GOPATH=`pwd`/go ' +
     'GOARCH=arm ' +
@@ -46,8 +89,12 @@ gulp.task('build-go-armhf', ['clean', 'move-click', 'move-scope'], shell.task(
     '-ldflags \'-extld=arm-linux-gnueabihf-g++\' ' + paths.src.go
 ));
 
+gulp.task('settings', ['build-go'], shell.task(
+    paths.dist.go + ' --write-settings src/falcon.bhdouglass_falcon-settings.ini'
+));
+
 gulp.task('run', ['build-go'], shell.task(
-    'unity-scope-tool ' + paths.dist.scope + '/falcon.bhdouglass_falcon.ini'
+    'unity-scope-tool ' + paths.dist.ini
 ));
 
 gulp.task('default', ['run']);
@@ -2,12 +2,11 @@ package main
 
 import (
     "encoding/json"
+    "errors"
     "fmt"
-    "github.com/gosexy/gettext"
     "io/ioutil"
     "launchpad.net/go-unityscopes/v2"
-    "log"
-    "os"
+    "path/filepath"
     "sort"
     "strings"
 )
@@ -30,86 +29,92 @@ const searchCategoryTemplate = `{
 }`
 
 func (falcon *Falcon) firstChar(str string) string {
+    //Apps without a title are grouped under #
+    if str == "" {
+        return "#"
+    }
+
     return string([]rune(str)[0])
 }
 
-func (falcon *Falcon) addApps(query string, reply *scopes.SearchReply) error {
-    var settings Settings
-    falcon.base.Settings(&settings)
+func isCancelled(cancelled <-chan bool) bool {
+    select {
+    case <-cancelled:
+        return true
+    default:
+        return false
+    }
+}
 
-    paths := []string{
-        "/usr/share/applications/",
-        "/home/phablet/.local/share/applications/",
+func (falcon *Falcon) appSubtitle(locale string, app Application) string {
+    if (app.Container != "") {
+        return falcon.tr(locale, "Desktop App")
+    } else if (app.IsWebapp) {
+        return falcon.tr(locale, "Web App")
+    } else if (app.IsApp) {
+        return falcon.tr(locale, "App")
     }
 
+    return falcon.tr(locale, "Scope")
+}
+
+func (falcon *Falcon) setResult(result scopes.ResultSetter, app Application) {
+    result.SetURI(app.Uri)
+    result.SetTitle(app.Title)
+    result.SetArt(app.Icon)
+    result.Set("app", newAppPayload(app))
+    result.SetInterceptActivation()
+}
+
+func (falcon *Falcon) addApps(query string, locale string, reply scopes.SearchReplier, cancelled <-chan bool) error {
+    //Use the same favorites and settings for the whole search
+    state := falcon.store.Snapshot()
+    settings := state.Settings
+
     var uappexplorer Application
     var uappexplorerScope Application
     var clickstore Application
 
+    //Titles are translated to the locale of the request, like the rest of the results, so they match the cache key
+    langs := localeLanguages(locale)
+
     var appList Applications
-    for index := range paths {
-        path := paths[index]
+    //Every app found is kept so results can be resolved to the full Application, not just those matching the query
+    apps := map[string]Application{}
+    for index := range falcon.appDirs {
+        path := falcon.appDirs[index]
         files, err := ioutil.ReadDir(path)
         if err != nil {
-            log.Println(err)
+            falcon.log.Warn("could not read app directory", "dir", path, "error", err)
         } else {
             for _, f := range files {
-                content, err := ioutil.ReadFile(path + f.Name())
+                if isCancelled(cancelled) {
+                    return nil
+                }
+
+                content, err := ioutil.ReadFile(filepath.Join(path, f.Name()))
                 if err != nil {
-                    log.Fatalln(err)
+                    //One broken file shouldn't hide every other app
+                    falcon.log.Warn("skipping unreadable desktop file", "file", filepath.Join(path, f.Name()), "error", err)
                 } else {
-                    lines := strings.Split(string(content), "\n")
-
                     var app = Application{}
                     app.Desktop = string(content)
                     app.Uri = "application:///" + f.Name()
                     app.IsApp = true
+                    app.DesktopDir = path
 
                     skip := true
                     nodisplay := false
                     onlyShowIn := "unity"
 
-                    desktopMap := map[string] string{}
-                    for _, line := range lines {
-                        split := strings.Split(line, "=")
-                        if (len(split) >= 2) {
-                            key := split[0]
-                            lkey := strings.ToLower(key)
-                            value := strings.Replace(line, key + "=", "", 1)
-
-                            desktopMap[lkey] = value
-                        }
+                    //Only the main entry describes the app, the other groups are its quick actions
+                    desktopMap := parseDesktopFile(string(content))[desktopEntryGroup]
+                    if desktopMap == nil {
+                        desktopMap = map[string]string{}
                     }
 
-                    if value, ok := desktopMap["name"]; ok {
-                        app.Title = value
-
-                        fullLang := os.Getenv("LANG")
-                        if (fullLang != "") {
-                            split := strings.Split(fullLang, ".")
-                            lang := strings.ToLower(split[0])
-
-                            split = strings.Split(lang, "_")
-                            shortLang := strings.ToLower(split[0])
-
-                            if title, ok := desktopMap[fmt.Sprintf("name[%s]", lang)]; ok {
-                                app.Title = title
-                            } else if title, ok := desktopMap[fmt.Sprintf("name[%s]", shortLang)]; ok {
-                                app.Title = title
-                            }
-                        }
-
-                        if domain, ok := desktopMap["x-ubuntu-gettext-domain"]; ok {
-                            gettext.BindTextdomain(domain, ".")
-                            gettext.Textdomain(domain)
-                            gettext.SetLocale(gettext.LC_ALL, "")
-
-                            translation := gettext.Gettext(value)
-                            if (translation != "") {
-                                app.Title = translation
-                            }
-                        }
-
+                    if _, ok := desktopMap["name"]; ok {
+                        app.Title = falcon.localizedName(desktopMap, desktopMap, langs)
                         app.Sort = strings.ToLower(app.Title)
                     }
 
@@ -123,10 +128,16 @@ func (falcon *Falcon) addApps(query string, reply *scopes.SearchReply) error {
                         }
                     }
 
+                    if value, ok := desktopMap["categories"]; ok {
+                        app.Category = mainCategory(value)
+                    }
+
                     if value, ok := desktopMap["comment"]; ok {
                         app.Comment = value
                     }
 
+                    app.IsWebapp, app.WebappUrl = webapp(desktopMap)
+
                     if value, ok := desktopMap["x-ubuntu-application-id"]; ok {
                         app.Id = strings.ToLower(value)
                     }
@@ -161,6 +172,9 @@ func (falcon *Falcon) addApps(query string, reply *scopes.SearchReply) error {
                     }
 
                     if (!skip && !nodisplay && onlyShowIn == "unity") {
+                        apps[app.Uri] = app
+                        app = state.applyOverride(app)
+
                         if (strings.Contains(app.Id, "uappexplorer.bhdouglass")) {
                             uappexplorer = app
                         } else if (strings.Contains(app.Id, "uappexplorer-scope.bhdouglass")) {
@@ -178,13 +192,25 @@ func (falcon *Falcon) addApps(query string, reply *scopes.SearchReply) error {
         }
     }
 
-    //Remote scopes
-    file, err := ioutil.ReadFile("/home/phablet/.cache/unity-scopes/remote-scopes.json")
-    if err != nil {
-        log.Println(err)
+    for _, app := range falcon.libertineApps(langs, cancelled) {
+        apps[app.Uri] = app
+        app = state.applyOverride(app)
+
+        if (query == "" || strings.Index(strings.ToLower(app.Title), strings.ToLower(query)) >= 0) {
+            appList = append(appList, app)
+        }
+    }
+
+    //Remote scopes, falcon-cli has no file to read them from unless it's given one
+    if falcon.remoteScopesFile == "" {
+        falcon.log.Debug("no remote scopes file to read")
+    } else if file, err := ioutil.ReadFile(falcon.remoteScopesFile); err != nil {
+        falcon.log.Warn("could not read remote scopes", "file", falcon.remoteScopesFile, "error", err)
     } else {
         var remoteScopes []RemoteScope
-        json.Unmarshal(file, &remoteScopes)
+        if err := json.Unmarshal(file, &remoteScopes); err != nil {
+            falcon.log.Warn("could not parse remote scopes", "file", falcon.remoteScopesFile, "error", err)
+        }
 
         for index := range remoteScopes {
             remoteScope := remoteScopes[index]
@@ -197,6 +223,8 @@ func (falcon *Falcon) addApps(query string, reply *scopes.SearchReply) error {
             scope.Icon = remoteScope.Icon
             scope.Uri = fmt.Sprintf("scope://%s", remoteScope.Id)
             scope.IsApp = false
+            apps[scope.Uri] = scope
+            scope = state.applyOverride(scope)
 
             if (query == "" || strings.Index(strings.ToLower(scope.Title), strings.ToLower(query)) >= 0) {
                 appList = append(appList, scope)
@@ -204,16 +232,36 @@ func (falcon *Falcon) addApps(query string, reply *scopes.SearchReply) error {
         }
     }
 
+    if isCancelled(cancelled) {
+        return nil
+    }
+
+    falcon.storeApps(apps)
     sort.Sort(appList)
 
     categories := map[string] *scopes.Category{};
 
     //TODO have an option to make this a different layout
-    categories["favorite"] = reply.RegisterCategory("favorites", "Favorites", "", searchCategoryTemplate)
+    categories["favorite"] = reply.RegisterCategory("favorites", falcon.tr(locale, "Favorites"), "", searchCategoryTemplate)
+
+    if (settings.Layout == layoutAppsScopes) { //Group by apps & scopes
+        categories["apps"] = reply.RegisterCategory("apps", falcon.tr(locale, "Apps"), "", searchCategoryTemplate)
+        if (settings.Webapps) {
+            categories["web-apps"] = reply.RegisterCategory("web-apps", falcon.tr(locale, "Web Apps"), "", searchCategoryTemplate)
+        }
 
-    if (settings.Layout == 0) { //Group by apps & scopes
-        categories["apps"] = reply.RegisterCategory("apps", "Apps", "", searchCategoryTemplate)
-        categories["scopes"] = reply.RegisterCategory("scopes", "Scopes", "", searchCategoryTemplate)
+        //Most devices don't have any desktop apps
+        for index := range appList {
+            if (appList[index].Container != "") {
+                categories["desktop-apps"] = reply.RegisterCategory("desktop-apps", falcon.tr(locale, "Desktop Apps"), "", searchCategoryTemplate)
+                break
+            }
+        }
+        categories["scopes"] = reply.RegisterCategory("scopes", falcon.tr(locale, "Scopes"), "", searchCategoryTemplate)
+    } else if (settings.Layout == layoutCategories) { //Group by freedesktop category
+        for _, category := range usedCategories(appList) {
+            categories[category] = reply.RegisterCategory(strings.ToLower(category), falcon.categoryTitle(locale, category), "", searchCategoryTemplate)
+        }
     } else { //Group by first letter
         //TODO ignore A/An/The
         //TODO group numbers
@@ -236,85 +284,108 @@ func (falcon *Falcon) addApps(query string, reply *scopes.SearchReply) error {
         }
     }
 
-    searchTitle := "Search for more apps"
+    searchTitle := falcon.tr(locale, "Search for more apps")
     if (query != "") {
-        searchTitle = fmt.Sprintf("Search for apps like \"%s\"", query)
+        searchTitle = fmt.Sprintf(falcon.tr(locale, "Search for apps like \"%s\""), query)
     }
     storeCategory := reply.RegisterCategory("store", searchTitle, "", searchCategoryTemplate)
 
+    //Results are collected and pushed together to avoid a round trip to the scopes runtime for each one
+    var results []scopes.ResultSetter
+
     for index := range appList {
+        if isCancelled(cancelled) {
+            return nil
+        }
+
         app := appList[index]
 
-        if falcon.isFavorite(app.Id) {
-            result := scopes.NewCategorisedResult(categories["favorite"])
-            result.SetURI(app.Uri)
-            result.SetTitle(app.Title)
-            result.SetArt(app.Icon)
-            result.Set("app", app)
-            result.SetInterceptActivation()
+        if state.IsFavorite(app.Id) {
+            result := reply.NewResult(categories["favorite"])
+            falcon.setResult(result, app)
+            results = append(results, result)
+        }
+    }
+
+    //The categories are pushed one after the other, so each app is pushed in the order of its category
+    if (settings.Layout == layoutCategories) {
+        for _, category := range usedCategories(appList) {
+            for index := range appList {
+                if isCancelled(cancelled) {
+                    return nil
+                }
+
+                app := appList[index]
+                if (appCategory(app) != category || state.IsFavorite(app.Id)) {
+                    continue
+                }
+
+                result := reply.NewResult(categories[category])
+                //Desktop apps and webapps are mixed in with the phone's apps, so badge them
+                if (app.Container != "" || app.IsWebapp) {
+                    result.Set("subtitle", falcon.appSubtitle(locale, app))
+                }
 
-            if err := reply.Push(result); err != nil {
-                log.Fatalln(err)
+                falcon.setResult(result, app)
+                results = append(results, result)
             }
         }
     }
 
     for index := range appList {
+        if isCancelled(cancelled) {
+            return nil
+        }
+
         app := appList[index]
 
         //See note at next for loop
-        if (settings.Layout == 0 && !app.IsApp) || falcon.isFavorite(app.Id) {
+        if settings.Layout == layoutCategories || (settings.Layout == layoutAppsScopes && !app.IsApp) || state.IsFavorite(app.Id) {
             continue
         }
 
-        var result *scopes.CategorisedResult
-        if (settings.Layout == 0) {
-            if (app.IsApp) {
-                result = scopes.NewCategorisedResult(categories["apps"])
+        var result scopes.ResultSetter
+        if (settings.Layout == layoutAppsScopes) {
+            if (app.Container != "") {
+                result = reply.NewResult(categories["desktop-apps"])
+            } else if (app.IsWebapp && settings.Webapps) {
+                result = reply.NewResult(categories["web-apps"])
+            } else if (app.IsApp) {
+                result = reply.NewResult(categories["apps"])
+
+                if (app.IsWebapp) {
+                    result.Set("subtitle", falcon.appSubtitle(locale, app))
+                }
             } else {
-                result = scopes.NewCategorisedResult(categories["scopes"])
+                result = reply.NewResult(categories["scopes"])
             }
         } else {
             char := strings.ToUpper(falcon.firstChar(app.Title))
-            result = scopes.NewCategorisedResult(categories[char])
+            result = reply.NewResult(categories[char])
 
-            if (app.IsApp) {
-                result.Set("subtitle", "App")
-            } else {
-                result.Set("subtitle", "Scope")
-            }
+            result.Set("subtitle", falcon.appSubtitle(locale, app))
         }
 
-        result.SetURI(app.Uri)
-        result.SetTitle(app.Title)
-        result.SetArt(app.Icon)
-        result.Set("app", app)
-        result.SetInterceptActivation()
-
-        if err := reply.Push(result); err != nil {
-            log.Fatalln(err)
-        }
+        falcon.setResult(result, app)
+        results = append(results, result)
     }
 
     //TODO This is a really hacky looking way to make sure the apps go before the scopes, figure out a better way to do this
-    if (settings.Layout == 0) {
+    if (settings.Layout == layoutAppsScopes) {
         for index := range appList {
+            if isCancelled(cancelled) {
+                return nil
+            }
+
             app := appList[index]
 
-            if (app.IsApp || falcon.isFavorite(app.Id)) {
+            if (app.IsApp || state.IsFavorite(app.Id)) {
                 continue
             }
 
-            result := scopes.NewCategorisedResult(categories["scopes"])
-            result.SetURI(app.Uri)
-            result.SetTitle(app.Title)
-            result.SetArt(app.Icon)
-            result.Set("app", app)
-            result.SetInterceptActivation()
-
-            if err := reply.Push(result); err != nil {
-                log.Fatalln(err)
-            }
+            result := reply.NewResult(categories["scopes"])
+            falcon.setResult(result, app)
+            results = append(results, result)
         }
     }
 
@@ -342,16 +413,18 @@ func (falcon *Falcon) addApps(query string, reply *scopes.SearchReply) error {
     }
 
     if (store.Id != "") {
-        result := scopes.NewCategorisedResult(storeCategory)
-        result.SetURI(store.Uri)
-        result.SetTitle(store.Title)
-        result.SetArt(store.Icon)
-        result.Set("app", store)
-        result.SetInterceptActivation()
-
-        if err := reply.Push(result); err != nil {
-            log.Fatalln(err)
+        result := reply.NewResult(storeCategory)
+        falcon.setResult(result, store)
+        results = append(results, result)
+    }
+
+    if err := scopes.PushResults(reply, results...); err != nil {
+        //The query was cancelled or timed out while the results were being pushed, which isn't an error
+        if errors.Is(err, scopes.ErrReplyClosed) {
+            return nil
         }
+
+        return err
     }
 
     return nil
@@ -0,0 +1,96 @@
+package main
+
+import (
+    "encoding/json"
+    "launchpad.net/go-unityscopes/v2"
+)
+
+//Bump this when the fields of AppPayload change, older payloads must still be resolvable
+const appPayloadVersion = 1
+
+//Only the fields needed to find the app again are stored in each result, the full Application lives in the store
+func newAppPayload(app Application) AppPayload {
+    return AppPayload{
+        Version: appPayloadVersion,
+        Id: app.Id,
+        Uri: app.Uri,
+        Title: app.Title,
+        Icon: app.Icon,
+        IsApp: app.IsApp,
+    }
+}
+
+func (payload AppPayload) app() Application {
+    return Application{
+        Id: payload.Id,
+        Uri: payload.Uri,
+        Title: payload.Title,
+        Icon: payload.Icon,
+        IsApp: payload.IsApp,
+    }
+}
+
+func (falcon *Falcon) storeApps(apps map[string]Application) {
+    falcon.store.Update(func(state *State) error {
+        state.Apps = apps
+        return nil
+    })
+}
+
+//lookupApp returns an app found by the last search, as the user has customized it since
+func (falcon *Falcon) lookupApp(uri string) (Application, bool) {
+    state := falcon.store.Snapshot()
+    app, ok := state.Apps[uri]
+    if !ok {
+        return app, false
+    }
+
+    return state.applyOverride(app), true
+}
+
+//payloadApp resolves the "app" attribute of a result to the full Application
+func (falcon *Falcon) payloadApp(data []byte) Application {
+    var version struct {
+        Version int `json:"v"`
+    }
+    if err := json.Unmarshal(data, &version); err != nil {
+        falcon.log.Warn("could not read app payload", "error", err)
+        return Application{}
+    }
+
+    //Results from before the payload was versioned embed the whole Application
+    if version.Version == 0 {
+        var app Application
+        if err := json.Unmarshal(data, &app); err != nil {
+            falcon.log.Warn("could not read app payload", "version", 0, "error", err)
+        }
+
+        return app
+    }
+
+    if version.Version > appPayloadVersion {
+        falcon.log.Warn("unknown app payload version", "version", version.Version)
+    }
+
+    var payload AppPayload
+    if err := json.Unmarshal(data, &payload); err != nil {
+        falcon.log.Warn("could not read app payload", "version", version.Version, "error", err)
+    }
+
+    if app, ok := falcon.lookupApp(payload.Uri); ok {
+        return app
+    }
+
+    //The app is no longer installed or hasn't been seen since Falcon started, so use what the result knows
+    return payload.app()
+}
+
+func (falcon *Falcon) resultApp(result *scopes.Result) Application {
+    var data json.RawMessage
+    if err := result.Get("app", &data); err != nil {
+        falcon.log.Warn("result has no app payload", "uri", result.URI(), "error", err)
+        return Application{}
+    }
+
+    return falcon.payloadApp(data)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+    "encoding/json"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+func TestPayloadApp(t *testing.T) {
+    app := Application{
+        Id: "com.ubuntu.calculator_calculator_2.0",
+        Title: "Calculator",
+        Comment: "A simple calculator",
+        Icon: "file:///calculator.svg",
+        Uri: "application:///calculator.desktop",
+        Desktop: "[Desktop Entry]\nName=Calculator\n",
+        IsApp: true,
+        Sort: "calculator",
+    }
+
+    payload, err := json.Marshal(newAppPayload(app))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    legacy, err := json.Marshal(app)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    falcon := newFalcon()
+
+    //Without the app in the store only the fields in the payload are known
+    resolved := falcon.payloadApp(payload)
+    if resolved.Id != app.Id || resolved.Uri != app.Uri || resolved.Title != app.Title || !resolved.IsApp || resolved.Comment != "" {
+        t.Errorf("unexpected app from payload: %+v", resolved)
+    }
+
+    falcon.storeApps(map[string]Application{app.Uri: app})
+    if resolved := falcon.payloadApp(payload); resolved != app {
+        t.Errorf("expected %+v from store, got %+v", app, resolved)
+    }
+
+    //Results from before the payload was versioned carry the whole app
+    falcon.storeApps(nil)
+    if resolved := falcon.payloadApp(legacy); resolved != app {
+        t.Errorf("expected %+v from legacy payload, got %+v", app, resolved)
+    }
+
+    if len(payload) >= len(legacy) {
+        t.Errorf("payload is not smaller than the full app: %s", payload)
+    }
+}
+
+func TestMissingFavoritesFile(t *testing.T) {
+    dir, err := ioutil.TempDir("", "falcon-favorites")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+
+    falcon := newFalcon()
+    file := filepath.Join(dir, "favorites.txt")
+    falcon.loadFavorites(file)
+    if favorites := falcon.store.Snapshot().Favorites; len(favorites) != 0 {
+        t.Errorf("expected no favorites, got %v", favorites)
+    }
+
+    if err := falcon.favorite("calculator"); err != nil {
+        t.Fatal(err)
+    }
+
+    falcon.loadFavorites(file)
+    if state := falcon.store.Snapshot(); !state.IsFavorite("calculator") {
+        t.Errorf("expected calculator to be saved as a favorite, got %v", state.Favorites)
+    }
+
+    //A favorite that can't be saved is reported instead of stopping Falcon
+    falcon.loadFavorites(filepath.Join(dir, "missing", "favorites.txt"))
+    if err := falcon.favorite("terminal"); err == nil {
+        t.Error("expected an error saving to a missing directory")
+    }
+
+    if falcon.store.Snapshot().IsFavorite("terminal") {
+        t.Error("expected a favorite that wasn't saved to be dropped")
+    }
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+    "strings"
+)
+
+//The registered main categories of the freedesktop menu specification, in the order they are shown
+var mainCategories = []string{
+    "AudioVideo",
+    "Development",
+    "Education",
+    "Game",
+    "Graphics",
+    "Network",
+    "Office",
+    "Science",
+    "Settings",
+    "System",
+    "Utility",
+}
+
+//Audio and Video must be listed with AudioVideo, but apps don't always follow the spec
+var categoryAliases = map[string]string{
+    "Audio": "AudioVideo",
+    "Video": "AudioVideo",
+}
+
+const (
+    //Apps without a main category
+    categoryOther = "Other"
+    categoryScopes = "scopes"
+)
+
+//mainCategory returns the first main category in the value of a desktop file's Categories key, or categoryOther
+func mainCategory(value string) string {
+    for _, category := range strings.Split(value, ";") {
+        category = strings.TrimSpace(category)
+        if alias, ok := categoryAliases[category]; ok {
+            category = alias
+        }
+
+        for _, main := range mainCategories {
+            if category == main {
+                return main
+            }
+        }
+    }
+
+    return categoryOther
+}
+
+//appCategory returns the group an app is shown in by the categories layout
+func appCategory(app Application) string {
+    if !app.IsApp {
+        return categoryScopes
+    }
+
+    if app.Category == "" {
+        return categoryOther
+    }
+
+    return app.Category
+}
+
+//usedCategories returns the groups of the categories layout that have apps, in the order they are shown
+func usedCategories(apps Applications) []string {
+    used := map[string]bool{}
+    for _, app := range apps {
+        used[appCategory(app)] = true
+    }
+
+    var categories []string
+    for _, category := range append(mainCategories, categoryOther, categoryScopes) {
+        if used[category] {
+            categories = append(categories, category)
+        }
+    }
+
+    return categories
+}
+
+//categoryTitle returns the localized title of a group in the categories layout
+func (falcon *Falcon) categoryTitle(locale string, category string) string {
+    switch category {
+    case "AudioVideo":
+        return falcon.tr(locale, "Sound & Video")
+    case "Development":
+        return falcon.tr(locale, "Programming")
+    case "Education":
+        return falcon.tr(locale, "Education")
+    case "Game":
+        return falcon.tr(locale, "Games")
+    case "Graphics":
+        return falcon.tr(locale, "Graphics")
+    case "Network":
+        return falcon.tr(locale, "Internet")
+    case "Office":
+        return falcon.tr(locale, "Office")
+    case "Science":
+        return falcon.tr(locale, "Science")
+    case "Settings":
+        return falcon.tr(locale, "Settings")
+    case "System":
+        return falcon.tr(locale, "System")
+    case "Utility":
+        return falcon.tr(locale, "Accessories")
+    case categoryScopes:
+        return falcon.tr(locale, "Scopes")
+    }
+
+    return falcon.tr(locale, "Other")
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+    "reflect"
+    "testing"
+)
+
+func TestMainCategory(t *testing.T) {
+    cases := map[string]string{
+        "Game;ArcadeGame;": "Game",
+        "Qt;KDE;Office;WordProcessor;": "Office",
+        "Audio;Player;": "AudioVideo",
+        "TerminalEmulator;System;Utility;": "System",
+        "X-Ubuntu-Custom;": categoryOther,
+        "": categoryOther,
+    }
+
+    for value, expected := range cases {
+        if category := mainCategory(value); category != expected {
+            t.Errorf("expected %q for %q, got %q", expected, value, category)
+        }
+    }
+
+    apps := Applications{
+        {Title: "Weather", IsApp: false},
+        {Title: "Notes", IsApp: true},
+        {Title: "Chess", IsApp: true, Category: "Game"},
+        {Title: "Browser", IsApp: true, Category: "Network"},
+    }
+    if categories, expected := usedCategories(apps), []string{"Game", "Network", categoryOther, categoryScopes}; !reflect.DeepEqual(categories, expected) {
+        t.Errorf("expected %v, got %v", expected, categories)
+    }
+}
@@ -0,0 +1,320 @@
+// +build cli
+
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "flag"
+    "fmt"
+    "launchpad.net/go-unityscopes/v2"
+    "launchpad.net/go-unityscopes/v2/scopestest"
+    "os"
+    "sort"
+    "strings"
+    "text/tabwriter"
+)
+
+//falcon-cli runs Falcon's search, preview and action logic outside of the dash
+//Results and previews are collected with the fake replies from scopestest, so it doesn't need the scopes runtime
+//Build it without cgo: CGO_ENABLED=0 go build -tags cli -o falcon-cli ./src
+
+//The scopes package registers the runtime's flags on the default flag set, falcon-cli has its own so they aren't listed
+var cliFlags = flag.NewFlagSet("falcon-cli", flag.ExitOnError)
+
+var (
+    cliDirs = cliFlags.String("dirs", "/usr/share/applications/,/home/phablet/.local/share/applications/", "Comma separated list of directories to read .desktop files from")
+    cliRemoteScopes = cliFlags.String("remote-scopes", "", "Remote scopes json file to read, no remote scopes are listed without one")
+    cliLibertine = cliFlags.String("libertine", libertineContainerDir(), "Directory of Libertine containers to read desktop apps from")
+    cliFavorites = cliFlags.String("favorites", "", "Favorites file to read and update")
+    cliOverrides = cliFlags.String("overrides", "", "File of app titles and icons to read and update")
+    cliLayout = cliFlags.Int64("layout", layoutAppsScopes, "Layout setting to search with")
+    cliJson = cliFlags.Bool("json", false, "Print output as JSON")
+    cliLocale = cliFlags.String("locale", "", "Locale to translate Falcon's strings and app names to, such as de_DE")
+    cliLocaleDir = cliFlags.String("locale-dir", "", "Directory to read Falcon's translations from")
+)
+
+var activationStatusNames = map[scopes.ActivationStatus]string{
+    scopes.ActivationNotHandled: "not handled",
+    scopes.ActivationShowDash: "show dash",
+    scopes.ActivationHideDash: "hide dash",
+    scopes.ActivationShowPreview: "show preview",
+    scopes.ActivationPerformQuery: "perform query",
+}
+
+func cliGet(result *scopes.ResultBuilder, attr string) string {
+    var value string
+    result.Get(attr, &value)
+
+    return value
+}
+
+func cliPayload(result *scopes.ResultBuilder) (AppPayload, json.RawMessage) {
+    var payload AppPayload
+    var data json.RawMessage
+    if result.Get("app", &data) == nil {
+        json.Unmarshal(data, &payload)
+    }
+
+    return payload, data
+}
+
+func cliSearch(falcon *Falcon, query string) (*scopestest.SearchReply, error) {
+    reply := scopestest.NewSearchReply()
+    q := scopes.NewCannedQuery("falcon.bhdouglass_falcon", query, "")
+    if err := falcon.Search(q, scopes.NewSearchMetadata(0, *cliLocale, "phone"), reply, nil); err != nil {
+        return nil, err
+    }
+
+    return reply, reply.Err
+}
+
+func cliFindApp(falcon *Falcon, id string) (Application, error) {
+    reply, err := cliSearch(falcon, "")
+    if err != nil {
+        return Application{}, err
+    }
+
+    for _, result := range reply.Results {
+        payload, data := cliPayload(result)
+        if payload.Id == id || payload.Uri == id {
+            return falcon.payloadApp(data), nil
+        }
+    }
+
+    return Application{}, fmt.Errorf("no result with id or uri %q", id)
+}
+
+func printJson(value interface{}) error {
+    data, err := json.MarshalIndent(value, "", "    ")
+    if err != nil {
+        return err
+    }
+
+    fmt.Println(string(data))
+    return nil
+}
+
+func printSearch(reply *scopestest.SearchReply) error {
+    if *cliJson {
+        type jsonCategory struct {
+            Id string `json:"id"`
+            Title string `json:"title"`
+            Results []map[string]interface{} `json:"results"`
+        }
+
+        var categories []jsonCategory
+        for _, category := range reply.Categories {
+            jc := jsonCategory{Id: category.Id(), Title: category.Title(), Results: []map[string]interface{}{}}
+            for _, result := range reply.ResultsInCategory(category.Id()) {
+                attrs, err := result.Attributes()
+                if err != nil {
+                    return err
+                }
+
+                jc.Results = append(jc.Results, attrs)
+            }
+
+            categories = append(categories, jc)
+        }
+
+        return printJson(categories)
+    }
+
+    w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+    for _, category := range reply.Categories {
+        fmt.Fprintf(w, "%s [%s]\n", category.Title(), category.Id())
+        for _, result := range reply.ResultsInCategory(category.Id()) {
+            payload, _ := cliPayload(result)
+            fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", cliGet(result, "title"), cliGet(result, "subtitle"), payload.Id, cliGet(result, "uri"))
+        }
+    }
+
+    return w.Flush()
+}
+
+func printPreview(reply *scopestest.PreviewReply) error {
+    if *cliJson {
+        return printJson(reply.Widgets)
+    }
+
+    w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+    for _, widget := range reply.Widgets {
+        fmt.Fprintf(w, "%s [%s]\n", widget.Id(), widget.WidgetType())
+
+        var keys []string
+        for key := range widget {
+            if key != "id" && key != "type" {
+                keys = append(keys, key)
+            }
+        }
+        sort.Strings(keys)
+
+        for _, key := range keys {
+            data, err := json.Marshal(widget[key])
+            if err != nil {
+                return err
+            }
+
+            fmt.Fprintf(w, "  %s\t%s\n", key, data)
+        }
+    }
+
+    return w.Flush()
+}
+
+func cliReplay(falcon *Falcon, file string) error {
+    f, err := os.Open(file)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+
+    recording, err := scopes.ReadRecording(f)
+    if err != nil {
+        return err
+    }
+
+    replayed, err := scopes.Replay(falcon, recording)
+    if err != nil {
+        return err
+    }
+
+    diffs := scopes.DiffRecordings(recording, replayed)
+    if *cliJson {
+        if err := printJson(diffs); err != nil {
+            return err
+        }
+    } else {
+        for _, diff := range diffs {
+            fmt.Println(diff)
+        }
+    }
+
+    if len(diffs) > 0 {
+        return fmt.Errorf("%d differences from %s", len(diffs), file)
+    }
+
+    return nil
+}
+
+func runCli(falcon *Falcon, args []string) error {
+    if len(args) == 0 {
+        return errors.New("missing command")
+    }
+
+    switch args[0] {
+    case "search":
+        reply, err := cliSearch(falcon, strings.Join(args[1:], " "))
+        if err != nil {
+            return err
+        }
+
+        return printSearch(reply)
+
+    case "preview":
+        if len(args) != 2 {
+            return errors.New("usage: preview <id>")
+        }
+
+        app, err := cliFindApp(falcon, args[1])
+        if err != nil {
+            return err
+        }
+
+        reply := scopestest.NewPreviewReply()
+        if err := falcon.previewApp(app, *cliLocale, reply); err != nil {
+            return err
+        }
+
+        if reply.Err != nil {
+            return reply.Err
+        }
+
+        return printPreview(reply)
+
+    case "action":
+        if len(args) != 3 {
+            return errors.New("usage: action <id> <action>")
+        }
+
+        app, err := cliFindApp(falcon, args[1])
+        if err != nil {
+            return err
+        }
+
+        var resp *scopes.ActivationResponse
+        if args[2] == "activate" {
+            resp = falcon.activateApp(app)
+        } else {
+            if (args[2] == "favorite" || args[2] == "unfavorite") && falcon.store.Snapshot().FavFile == "" {
+                return errors.New("-favorites is required to change favorites")
+            }
+
+            if (args[2] == "reset" || strings.HasPrefix(args[2], overrideIconPrefix)) && falcon.store.Snapshot().OverridesFile == "" {
+                return errors.New("-overrides is required to change app titles and icons")
+            }
+
+            resp, err = falcon.performAppAction(app, args[2])
+            if err != nil {
+                return err
+            }
+        }
+
+        status := activationStatusNames[resp.Status]
+        if resp.Query != nil {
+            status = fmt.Sprintf("%s %s", status, resp.Query.ToURI())
+        }
+
+        if *cliJson {
+            return printJson(map[string]string{"status": status})
+        }
+
+        fmt.Println(status)
+        return nil
+
+    case "replay":
+        if len(args) != 2 {
+            return errors.New("usage: replay <recording>")
+        }
+
+        return cliReplay(falcon, args[1])
+    }
+
+    return fmt.Errorf("unknown command %q", args[0])
+}
+
+func main() {
+    cliFlags.Usage = func() {
+        fmt.Fprintf(os.Stderr, "Usage: %s [flags] search [query] | preview <id> | action <id> <action> | replay <recording>\n", os.Args[0])
+        cliFlags.PrintDefaults()
+    }
+    cliFlags.Parse(os.Args[1:])
+
+    falcon := newFalcon()
+    falcon.appDirs = strings.Split(*cliDirs, ",")
+    falcon.remoteScopesFile = *cliRemoteScopes
+    falcon.libertineDir = *cliLibertine
+    falcon.store.Update(func(state *State) error {
+        state.Settings.Layout = *cliLayout
+        return nil
+    })
+
+    if *cliFavorites != "" {
+        falcon.loadFavorites(*cliFavorites)
+    }
+
+    if *cliOverrides != "" {
+        falcon.loadOverrides(*cliOverrides)
+    }
+
+    if *cliLocaleDir != "" {
+        falcon.localeDir = *cliLocaleDir
+    }
+
+    if err := runCli(falcon, cliFlags.Args()); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        cliFlags.Usage()
+        os.Exit(1)
+    }
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+)
+
+//ClickPackage describes an installed click package, as shown in the details of an app's preview
+type ClickPackage struct {
+    Name string
+    Version string
+    Maintainer string
+    Framework string
+    //In KiB, as click measures it
+    InstalledSize int64
+    Installed time.Time
+    //The apparmor policy groups of the app, which are the permissions it asked for
+    PolicyGroups []string
+}
+
+type clickManifest struct {
+    Name string `json:"name"`
+    Version string `json:"version"`
+    Maintainer string `json:"maintainer"`
+    Framework string `json:"framework"`
+    //Click writes this as a string, but older versions used a number
+    InstalledSize json.Number `json:"installed-size"`
+    Hooks map[string]map[string]interface{} `json:"hooks"`
+}
+
+type clickApparmor struct {
+    PolicyGroups []string `json:"policy_groups"`
+}
+
+//splitAppId splits a click app id such as com.ubuntu.calculator_calculator_2.0 into its package, app and version
+func splitAppId(appId string) (string, string, string, bool) {
+    parts := strings.Split(appId, "_")
+    if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+        return "", "", "", false
+    }
+
+    return parts[0], parts[1], parts[2], true
+}
+
+//clickPackage reads the manifest of the click package an app was installed from, it returns nil if the app isn't from one
+func (falcon *Falcon) clickPackage(appId string) (*ClickPackage, error) {
+    pkg, app, version, ok := splitAppId(appId)
+    if !ok {
+        return nil, nil
+    }
+
+    for _, root := range falcon.clickRoots {
+        for _, dir := range []string{filepath.Join(root, pkg, version), filepath.Join(root, pkg, "current")} {
+            //The installed manifest has the installed size added by click, the one in the package doesn't
+            for _, file := range []string{filepath.Join(dir, ".click", "info", pkg + ".manifest"), filepath.Join(dir, "manifest.json")} {
+                info, err := os.Stat(file)
+                if err != nil {
+                    continue
+                }
+
+                return readClickPackage(dir, file, info.ModTime(), app)
+            }
+        }
+    }
+
+    return nil, nil
+}
+
+func readClickPackage(dir string, file string, installed time.Time, app string) (*ClickPackage, error) {
+    data, err := ioutil.ReadFile(file)
+    if err != nil {
+        return nil, err
+    }
+
+    var manifest clickManifest
+    if err := json.Unmarshal(data, &manifest); err != nil {
+        return nil, fmt.Errorf("could not parse %s: %s", file, err)
+    }
+
+    click := &ClickPackage{
+        Name: manifest.Name,
+        Version: manifest.Version,
+        Maintainer: manifest.Maintainer,
+        Framework: manifest.Framework,
+        Installed: installed,
+    }
+
+    if manifest.InstalledSize != "" {
+        if size, err := strconv.ParseInt(string(manifest.InstalledSize), 10, 64); err == nil {
+            click.InstalledSize = size
+        }
+    }
+
+    if apparmor, ok := manifest.Hooks[app]["apparmor"].(string); ok {
+        data, err := ioutil.ReadFile(filepath.Join(dir, apparmor))
+        if err != nil {
+            return click, err
+        }
+
+        var policy clickApparmor
+        if err := json.Unmarshal(data, &policy); err != nil {
+            return click, fmt.Errorf("could not parse %s: %s", apparmor, err)
+        }
+
+        click.PolicyGroups = policy.PolicyGroups
+    }
+
+    return click, nil
+}
+
+//formatSize formats a size in KiB for people to read
+func formatSize(kib int64) string {
+    switch {
+    case kib >= 1024 * 1024:
+        return fmt.Sprintf("%.1f GB", float64(kib) / (1024 * 1024))
+    case kib >= 1024:
+        return fmt.Sprintf("%.1f MB", float64(kib) / 1024)
+    }
+
+    return fmt.Sprintf("%d KB", kib)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+    "launchpad.net/go-unityscopes/v2"
+    "launchpad.net/go-unityscopes/v2/scopestest"
+    "os"
+    "path/filepath"
+    "reflect"
+    "testing"
+)
+
+func TestClickPackage(t *testing.T) {
+    falcon := newFalcon()
+    falcon.clickRoots = []string{filepath.Join("testdata", "click")}
+
+    manifest := filepath.Join("testdata", "click", "com.example.notes", "1.2.3", ".click", "info", "com.example.notes.manifest")
+    info, err := os.Stat(manifest)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    click, err := falcon.clickPackage("com.example.notes_notes_1.2.3")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    expected := &ClickPackage{
+        Name: "com.example.notes",
+        Version: "1.2.3",
+        Maintainer: "Jane Doe <jane@example.com>",
+        Framework: "ubuntu-sdk-15.04",
+        InstalledSize: 2560,
+        Installed: info.ModTime(),
+        PolicyGroups: []string{"networking", "content_exchange"},
+    }
+    if !reflect.DeepEqual(click, expected) {
+        t.Errorf("expected %+v, got %+v", expected, click)
+    }
+
+    //Apps that aren't from a click package have no details
+    for _, id := range []string{"webbrowser-app", "xenial_gimp_0.0", "com.example.missing_missing_1.0"} {
+        if click, err := falcon.clickPackage(id); click != nil || err != nil {
+            t.Errorf("expected no click package for %s, got %+v, %v", id, click, err)
+        }
+    }
+}
+
+//previewResult previews an app through the scope the way the shell would
+func previewResult(t *testing.T, falcon *Falcon, app Application) *scopestest.PreviewReply {
+    result, err := scopestest.NewResult(map[string]interface{}{
+        "uri": app.Uri,
+        "title": app.Title,
+        "app": newAppPayload(app),
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    reply := scopestest.NewPreviewReply()
+    if err := falcon.Preview(result, scopes.NewActionMetadata("C", "phone"), reply, nil); err != nil {
+        t.Fatal(err)
+    }
+
+    return reply
+}
+
+func TestPreviewDetails(t *testing.T) {
+    falcon := newTestFalcon(layoutAppsScopes)
+    falcon.clickRoots = []string{filepath.Join("testdata", "click")}
+
+    reply := previewResult(t, falcon, Application{Id: "com.example.notes_notes_1.2.3", Uri: "appid://com.example.notes/notes/1.2.3", Title: "Notes", IsApp: true})
+    if reply.Widget("header")["title"] != "Notes" {
+        t.Errorf("expected the app's title in the header, got %v", reply.Widget("header"))
+    }
+
+    details := reply.Widgets[len(reply.Widgets) - 1]
+    if details.Id() != "details" || details.WidgetType() != "expandable" {
+        t.Fatalf("expected the details to be the last widget, got %v", details)
+    }
+
+    table := details["widgets"].([]scopes.PreviewWidget)[0]
+    values := table["values"].([][]string)
+    if len(values) != 6 {
+        t.Errorf("expected 6 rows, got %q", values)
+    }
+
+    if expected := []string{"Permissions", "networking, content_exchange"}; !reflect.DeepEqual(values[5], expected) {
+        t.Errorf("expected %q, got %q", expected, values[5])
+    }
+
+    reply = previewResult(t, falcon, Application{Id: "webbrowser-app", Uri: "application:///webbrowser-app.desktop", Title: "Browser", IsApp: true})
+    if last := reply.Widgets[len(reply.Widgets) - 1]; last.Id() == "details" {
+        t.Error("expected no details for an app without a click package")
+    }
+}
+
+func TestFormatSize(t *testing.T) {
+    cases := map[int64]string{
+        512: "512 KB",
+        2560: "2.5 MB",
+        3 * 1024 * 1024: "3.0 GB",
+    }
+
+    for kib, expected := range cases {
+        if size := formatSize(kib); size != expected {
+            t.Errorf("expected %q for %d KiB, got %q", expected, kib, size)
+        }
+    }
+}
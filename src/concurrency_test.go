@@ -0,0 +1,68 @@
+package main
+
+import (
+    "io/ioutil"
+    "launchpad.net/go-unityscopes/v2"
+    "os"
+    "path/filepath"
+    "sync"
+    "testing"
+)
+
+//Run with -race to check Falcon's state is safe to use from concurrent queries. With the purego tag the scopes
+//package doesn't need libunity-scopes, so this runs anywhere cgo does: go test -race -tags purego
+func TestConcurrentQueries(t *testing.T) {
+    dir, err := ioutil.TempDir("", "falcon-concurrent")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+
+    falcon := newTestFalcon(layoutAppsScopes)
+    falcon.loadFavorites(filepath.Join(dir, "favorites.txt"))
+
+    //The recording has searches, a preview and activations
+    recording, _ := replayFile(t, falcon, filepath.Join("testdata", "layout-apps-scopes.jsonl"))
+
+    ids := []string{"calculator", "terminal", "settings", "camera"}
+
+    var wait sync.WaitGroup
+    for i := 0; i < 8; i++ {
+        wait.Add(1)
+        go func(i int) {
+            defer wait.Done()
+
+            id := ids[i % len(ids)]
+            for j := 0; j < 20; j++ {
+                switch j % 4 {
+                case 0:
+                    if err := falcon.favorite(id); err != nil {
+                        t.Error(err)
+                    }
+                case 1:
+                    if _, err := scopes.Replay(falcon, recording); err != nil {
+                        t.Error(err)
+                    }
+                case 2:
+                    if err := falcon.unfavorite(id); err != nil {
+                        t.Error(err)
+                    }
+                case 3:
+                    falcon.store.Update(func(state *State) error {
+                        state.Settings.Layout = int64(i % 2)
+                        return nil
+                    })
+                }
+            }
+        }(i)
+    }
+    wait.Wait()
+
+    //Every favorite was removed again, and no update was lost on the way
+    falcon.loadFavorites(filepath.Join(dir, "favorites.txt"))
+    for _, id := range falcon.store.Snapshot().Favorites {
+        if id != "" {
+            t.Errorf("unexpected favorite %q", id)
+        }
+    }
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "launchpad.net/go-unityscopes/v2"
+    "net/url"
+    "strings"
+)
+
+const desktopEntryGroup = "Desktop Entry"
+
+//Quick actions are identified in the preview's actions widget by this prefix and the id of their [Desktop Action] group
+const desktopActionPrefix = "desktop-action:"
+
+//DesktopAction is one of the [Desktop Action <id>] groups listed in the Actions key of a desktop file
+type DesktopAction struct {
+    Id string
+    Name string
+    Icon string
+    Exec string
+    Uri string
+}
+
+//parseDesktopFile splits a desktop file into its groups. Keys are lower cased so they can be matched case insensitively
+func parseDesktopFile(content string) map[string]map[string]string {
+    groups := map[string]map[string]string{}
+    group := map[string]string{}
+
+    for _, line := range strings.Split(content, "\n") {
+        trimmed := strings.TrimSpace(line)
+        if (strings.HasPrefix(trimmed, "#")) {
+            continue
+        }
+
+        if (strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]")) {
+            name := trimmed[1:len(trimmed) - 1]
+            if _, ok := groups[name]; !ok {
+                groups[name] = map[string]string{}
+            }
+
+            group = groups[name]
+            continue
+        }
+
+        split := strings.SplitN(line, "=", 2)
+        if (len(split) == 2) {
+            group[strings.ToLower(split[0])] = split[1]
+        }
+    }
+
+    return groups
+}
+
+//localizedName returns the Name of a desktop file group in the user's language. Translations come from the
+//Name[lang] keys and, for Ubuntu apps, the gettext catalog named by the main entry
+func (falcon *Falcon) localizedName(entry map[string]string, group map[string]string, langs []string) string {
+    value := group["name"]
+    name := value
+
+    for _, lang := range langs {
+        if localized, ok := group[fmt.Sprintf("name[%s]", strings.ToLower(lang))]; ok {
+            name = localized
+            break
+        }
+    }
+
+    if domain, ok := entry["x-ubuntu-gettext-domain"]; ok {
+        //Name[lang] is only used when the app's catalog has no translation
+        if translation := falcon.translations.Translate(appLocaleDirs(entry), domain, langs, value); translation != value {
+            name = translation
+        }
+    }
+
+    return name
+}
+
+//desktopActions returns the quick actions of an app, in the order they are listed in its Actions key
+func (falcon *Falcon) desktopActions(app Application, langs []string) []DesktopAction {
+    //The commands of desktop apps only exist inside their container
+    if app.Container != "" {
+        return nil
+    }
+
+    groups := parseDesktopFile(app.Desktop)
+    entry := groups[desktopEntryGroup]
+
+    var actions []DesktopAction
+    for _, id := range strings.Split(entry["actions"], ";") {
+        id = strings.TrimSpace(id)
+        group, ok := groups["Desktop Action " + id]
+        if id == "" || !ok || group["name"] == "" {
+            continue
+        }
+
+        //The scope can't start processes, so actions are opened by the url dispatcher
+        uri := execUri(group["exec"])
+        if uri == "" {
+            uri = actionUri(app, id)
+        }
+
+        action := DesktopAction{
+            Id: id,
+            Name: falcon.localizedName(entry, group, langs),
+            Exec: group["exec"],
+            Uri: uri,
+        }
+
+        if icon := group["icon"]; icon != "" && icon[0:1] == "/" {
+            action.Icon = "file://" + icon
+        }
+
+        actions = append(actions, action)
+    }
+
+    return actions
+}
+
+//parseExec splits the Exec key of a desktop file into arguments, dropping the field codes as no files or urls are passed
+func parseExec(value string) ([]string, error) {
+    var args []string
+    var arg []rune
+    inArg := false
+    quoted := false
+
+    runes := []rune(value)
+    for i := 0; i < len(runes); i++ {
+        r := runes[i]
+
+        switch {
+        case quoted && r == '\\' && i + 1 < len(runes):
+            i++
+            arg = append(arg, runes[i])
+        case r == '"':
+            quoted = !quoted
+            inArg = true
+        case !quoted && (r == ' ' || r == '\t'):
+            if inArg {
+                args = append(args, string(arg))
+            }
+
+            arg = nil
+            inArg = false
+        case r == '%' && i + 1 < len(runes):
+            i++
+            if runes[i] == '%' {
+                arg = append(arg, '%')
+                inArg = true
+            }
+        default:
+            arg = append(arg, r)
+            inArg = true
+        }
+    }
+
+    if quoted {
+        return nil, errors.New("unterminated quote in Exec")
+    }
+
+    if inArg {
+        args = append(args, string(arg))
+    }
+
+    if len(args) == 0 {
+        return nil, errors.New("empty Exec")
+    }
+
+    return args, nil
+}
+
+//execUri returns the first argument of an Exec key that is a uri, which the url dispatcher can open in the app
+func execUri(value string) string {
+    args, err := parseExec(value)
+    if err != nil {
+        return ""
+    }
+
+    for _, arg := range args[1:] {
+        if u, err := url.Parse(arg); err == nil && len(u.Scheme) > 1 {
+            return arg
+        }
+    }
+
+    return ""
+}
+
+//actionUri returns the uri an action without one of its own is opened by, which opens the app with the action's id
+func actionUri(app Application, id string) string {
+    uri := app.Uri
+    if pkg, name, _, ok := splitAppId(app.Id); ok {
+        uri = fmt.Sprintf("appid://%s/%s/current-user-version", pkg, name)
+    }
+
+    return uri + "?action=" + url.QueryEscape(id)
+}
+
+//dispatchLaunch is Falcon's default launcher, it opens an action's uri with the url dispatcher and hides the dash
+//so the app can be seen
+func dispatchLaunch(uri string) (*scopes.ActivationResponse, error) {
+    if err := dispatchUrl(uri); err != nil {
+        return nil, err
+    }
+
+    return scopes.NewActivationResponse(scopes.ActivationHideDash), nil
+}
+
+func (falcon *Falcon) launchDesktopAction(app Application, actionId string) (*scopes.ActivationResponse, error) {
+    for _, action := range falcon.desktopActions(app, nil) {
+        if action.Id == actionId {
+            falcon.log.Info("launching desktop action", "app", app.Uri, "action", actionId, "uri", action.Uri)
+            return falcon.launch(action.Uri)
+        }
+    }
+
+    return nil, fmt.Errorf("%s has no action %s", app.Uri, actionId)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+    "launchpad.net/go-unityscopes/v2"
+    "launchpad.net/go-unityscopes/v2/scopestest"
+    "reflect"
+    "testing"
+)
+
+const browserDesktop = `[Desktop Entry]
+Name=Browser
+Name[de]=Webbrowser
+Exec=webbrowser-app %u
+Icon=/usr/share/webbrowser-app/browser.png
+Actions=NewPrivateWindow;Missing;Compose
+X-Ubuntu-Touch=true
+
+# The actions' names must not replace the app's
+[Desktop Action NewPrivateWindow]
+Name=New private window
+Name[de]=Neues privates Fenster
+Exec=webbrowser-app --incognito "--title=Private %%"
+Icon=/usr/share/webbrowser-app/private.png
+
+[Desktop Action Compose]
+Name=Compose
+Name[de]=Verfassen
+Exec=webbrowser-app mailto:
+
+[Desktop Action Unlisted]
+Name=Unlisted
+Exec=webbrowser-app --unlisted
+`
+
+func TestParseDesktopFile(t *testing.T) {
+    groups := parseDesktopFile(browserDesktop)
+
+    if name := groups[desktopEntryGroup]["name"]; name != "Browser" {
+        t.Errorf("expected the main entry's name, got %q", name)
+    }
+
+    if name := groups["Desktop Action NewPrivateWindow"]["name[de]"]; name != "Neues privates Fenster" {
+        t.Errorf("unexpected action name %q", name)
+    }
+
+    if len(groups) != 4 {
+        t.Errorf("expected 4 groups, got %v", groups)
+    }
+}
+
+func TestDesktopActions(t *testing.T) {
+    falcon := newFalcon()
+    app := Application{Uri: "application:///webbrowser-app.desktop", Desktop: browserDesktop}
+
+    //Actions that don't open a uri of their own open the app with the action's id
+    expected := []DesktopAction{
+        {
+            Id: "NewPrivateWindow",
+            Name: "Neues privates Fenster",
+            Icon: "file:///usr/share/webbrowser-app/private.png",
+            Exec: `webbrowser-app --incognito "--title=Private %%"`,
+            Uri: "application:///webbrowser-app.desktop?action=NewPrivateWindow",
+        },
+        {Id: "Compose", Name: "Verfassen", Exec: "webbrowser-app mailto:", Uri: "mailto:"},
+    }
+    if actions := falcon.desktopActions(app, []string{"de_DE", "de"}); !reflect.DeepEqual(actions, expected) {
+        t.Errorf("expected %+v, got %+v", expected, actions)
+    }
+
+    var launched []string
+    falcon.launch = func(uri string) (*scopes.ActivationResponse, error) {
+        launched = append(launched, uri)
+        return scopes.NewActivationResponse(scopes.ActivationHideDash), nil
+    }
+
+    for _, id := range []string{"Compose", "NewPrivateWindow"} {
+        resp, err := falcon.performAppAction(app, desktopActionPrefix + id)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if resp.Status != scopes.ActivationHideDash {
+            t.Errorf("expected the dash to be hidden launching %s, got %v", id, resp.Status)
+        }
+    }
+
+    if expected := []string{"mailto:", expected[0].Uri}; !reflect.DeepEqual(launched, expected) {
+        t.Errorf("expected %q to be launched, got %q", expected, launched)
+    }
+
+    //Click apps are opened by their app id
+    click := app
+    click.Id = "com.example.browser_browser_1.0"
+    if uri := falcon.desktopActions(click, nil)[0].Uri; uri != "appid://com.example.browser/browser/current-user-version?action=NewPrivateWindow" {
+        t.Errorf("unexpected uri for a click app's action %q", uri)
+    }
+
+    //The buttons are labelled in the request's language, and have no uri so only PerformAction launches them
+    for locale, label := range map[string]string{"de_DE": "Verfassen", "C": "Compose"} {
+        reply := scopestest.NewPreviewReply()
+        if err := falcon.previewApp(app, locale, reply); err != nil {
+            t.Fatal(err)
+        }
+
+        actions := reply.Widget("actions")["actions"].([]ActionInfo)
+        if compose := actions[2]; compose.Id != desktopActionPrefix + "Compose" || compose.Uri != "" || compose.Label != label {
+            t.Errorf("expected the compose button labelled %q, got %+v", label, compose)
+        }
+    }
+
+    //Only listed actions can be launched
+    if _, err := falcon.performAppAction(app, desktopActionPrefix + "Unlisted"); err == nil {
+        t.Error("expected an error launching an unlisted action")
+    }
+}
+
+func TestParseExec(t *testing.T) {
+    cases := map[string][]string{
+        "app": {"app"},
+        "app %U": {"app"},
+        "app  --flag %f --other": {"app", "--flag", "--other"},
+        `app "with space" ""`: {"app", "with space", ""},
+        `app "quote \" and \\ backslash"`: {"app", `quote " and \ backslash`},
+        "app 100%%": {"app", "100%"},
+    }
+
+    for exec, expected := range cases {
+        args, err := parseExec(exec)
+        if err != nil {
+            t.Errorf("unexpected error parsing %q: %s", exec, err)
+        } else if !reflect.DeepEqual(args, expected) {
+            t.Errorf("expected %q for %q, got %q", expected, exec, args)
+        }
+    }
+
+    for _, exec := range []string{"", "%U", `app "unterminated`} {
+        if args, err := parseExec(exec); err == nil {
+            t.Errorf("expected an error parsing %q, got %q", exec, args)
+        }
+    }
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/binary"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io"
+    "net"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+)
+
+//The url dispatcher opens uris in the app that handles them. appid:// uris open the click app they name
+const (
+    urlDispatcherName = "com.canonical.URLDispatcher"
+    urlDispatcherPath = "/com/canonical/URLDispatcher"
+)
+
+//How long to wait for the session bus, the url dispatcher answers as soon as it has asked the app to open
+const dispatchTimeout = 5 * time.Second
+
+//D-Bus message types and header fields, from the D-Bus specification
+const (
+    dbusMethodCall = 1
+    dbusMethodReturn = 2
+    dbusError = 3
+
+    dbusFieldPath = 1
+    dbusFieldInterface = 2
+    dbusFieldMember = 3
+    dbusFieldErrorName = 4
+    dbusFieldReplySerial = 5
+    dbusFieldDestination = 6
+    dbusFieldSignature = 8
+)
+
+//dispatchUrl asks the url dispatcher to open a uri. The confined scope can't start processes, but it can talk to the
+//session bus, so this only needs enough of D-Bus to call a method with string arguments
+func dispatchUrl(uri string) error {
+    conn, err := dialSessionBus()
+    if err != nil {
+        return err
+    }
+    defer conn.Close()
+    conn.SetDeadline(time.Now().Add(dispatchTimeout))
+
+    reader := bufio.NewReader(conn)
+    if err := dbusAuth(conn, reader); err != nil {
+        return err
+    }
+
+    //Every connection must say hello before its other calls are answered
+    if _, err := dbusCall(conn, reader, 1, "org.freedesktop.DBus", "/org/freedesktop/DBus", "org.freedesktop.DBus", "Hello"); err != nil {
+        return err
+    }
+
+    //The second argument is the package asking, which only restricts the apps that may open the uri
+    _, err = dbusCall(conn, reader, 2, urlDispatcherName, urlDispatcherPath, urlDispatcherName, "DispatchURL", uri, "")
+    return err
+}
+
+//dialSessionBus connects to the first unix socket listed in DBUS_SESSION_BUS_ADDRESS
+func dialSessionBus() (net.Conn, error) {
+    address := os.Getenv("DBUS_SESSION_BUS_ADDRESS")
+    if address == "" {
+        address = fmt.Sprintf("unix:path=/run/user/%d/bus", os.Getuid())
+    }
+
+    for _, entry := range strings.Split(address, ";") {
+        if !strings.HasPrefix(entry, "unix:") {
+            continue
+        }
+
+        for _, param := range strings.Split(strings.TrimPrefix(entry, "unix:"), ",") {
+            if strings.HasPrefix(param, "path=") {
+                return net.Dial("unix", strings.TrimPrefix(param, "path="))
+            } else if strings.HasPrefix(param, "abstract=") {
+                return net.Dial("unix", "@" + strings.TrimPrefix(param, "abstract="))
+            }
+        }
+    }
+
+    return nil, fmt.Errorf("no unix socket in the session bus address %q", address)
+}
+
+//dbusAuth authenticates as the user running the scope, which the bus checks against the socket's credentials
+func dbusAuth(conn net.Conn, reader *bufio.Reader) error {
+    uid := hex.EncodeToString([]byte(strconv.Itoa(os.Getuid())))
+    if _, err := conn.Write([]byte("\x00AUTH EXTERNAL " + uid + "\r\n")); err != nil {
+        return err
+    }
+
+    line, err := reader.ReadString('\n')
+    if err != nil {
+        return err
+    }
+
+    if !strings.HasPrefix(line, "OK ") {
+        return fmt.Errorf("session bus refused authentication: %s", strings.TrimSpace(line))
+    }
+
+    _, err = conn.Write([]byte("BEGIN\r\n"))
+    return err
+}
+
+//dbusCall calls a method with string arguments and waits for its reply, returning the reply's body
+func dbusCall(conn net.Conn, reader *bufio.Reader, serial uint32, dest string, path string, iface string, member string, args ...string) ([]byte, error) {
+    if _, err := conn.Write(dbusMethodCallMessage(serial, dest, path, iface, member, args...)); err != nil {
+        return nil, err
+    }
+
+    //Signals such as NameAcquired can arrive before the reply
+    for {
+        msgType, fields, body, err := readDbusMessage(reader)
+        if err != nil {
+            return nil, err
+        }
+
+        if fields.replySerial != serial {
+            continue
+        }
+
+        if msgType == dbusError {
+            return nil, fmt.Errorf("%s failed: %s: %s", member, fields.errorName, dbusErrorMessage(body))
+        } else if msgType == dbusMethodReturn {
+            return body, nil
+        }
+    }
+}
+
+//dbusWriter marshals values little endian, aligned from the start of the message
+type dbusWriter struct {
+    bytes.Buffer
+}
+
+func (w *dbusWriter) align(n int) {
+    for w.Len() % n != 0 {
+        w.WriteByte(0)
+    }
+}
+
+func (w *dbusWriter) uint32(value uint32) {
+    w.align(4)
+    binary.Write(w, binary.LittleEndian, value)
+}
+
+func (w *dbusWriter) string(value string) {
+    w.uint32(uint32(len(value)))
+    w.WriteString(value)
+    w.WriteByte(0)
+}
+
+func (w *dbusWriter) signature(value string) {
+    w.WriteByte(byte(len(value)))
+    w.WriteString(value)
+    w.WriteByte(0)
+}
+
+//field writes a header field, which is a struct of its code and a variant of the value
+func (w *dbusWriter) field(code byte, signature string, value string) {
+    w.align(8)
+    w.WriteByte(code)
+    w.signature(signature)
+    if signature == "g" {
+        w.signature(value)
+    } else {
+        w.string(value)
+    }
+}
+
+func dbusMethodCallMessage(serial uint32, dest string, path string, iface string, member string, args ...string) []byte {
+    var body dbusWriter
+    for _, arg := range args {
+        body.string(arg)
+    }
+
+    var msg dbusWriter
+    msg.Write([]byte{'l', dbusMethodCall, 0, 1})
+    msg.uint32(uint32(body.Len()))
+    msg.uint32(serial)
+
+    var fields dbusWriter
+    //The fields are aligned from the start of the message, which they follow after the array's length at offset 16
+    fields.Write(make([]byte, 16))
+    fields.field(dbusFieldPath, "o", path)
+    fields.field(dbusFieldInterface, "s", iface)
+    fields.field(dbusFieldMember, "s", member)
+    fields.field(dbusFieldDestination, "s", dest)
+    if len(args) > 0 {
+        fields.field(dbusFieldSignature, "g", strings.Repeat("s", len(args)))
+    }
+
+    msg.uint32(uint32(fields.Len() - 16))
+    msg.Write(fields.Bytes()[16:])
+    msg.align(8)
+    msg.Write(body.Bytes())
+
+    return msg.Bytes()
+}
+
+type dbusFields struct {
+    replySerial uint32
+    errorName string
+}
+
+//readDbusMessage reads a message from the bus, keeping only the header fields needed to match replies
+func readDbusMessage(reader io.Reader) (byte, dbusFields, []byte, error) {
+    var fields dbusFields
+    fixed := make([]byte, 16)
+    if _, err := io.ReadFull(reader, fixed); err != nil {
+        return 0, fields, nil, err
+    }
+
+    var order binary.ByteOrder = binary.LittleEndian
+    if fixed[0] == 'B' {
+        order = binary.BigEndian
+    } else if fixed[0] != 'l' {
+        return 0, fields, nil, fmt.Errorf("invalid D-Bus message endianness %q", fixed[0])
+    }
+
+    bodyLen := order.Uint32(fixed[4:8])
+    fieldsLen := order.Uint32(fixed[12:16])
+    //The body starts at the next multiple of 8 after the fields
+    headerLen := (16 + fieldsLen + 7) / 8 * 8
+    if headerLen + bodyLen > 128 * 1024 * 1024 {
+        return 0, fields, nil, errors.New("D-Bus message too long")
+    }
+
+    msg := make([]byte, headerLen + bodyLen)
+    copy(msg, fixed)
+    if _, err := io.ReadFull(reader, msg[16:]); err != nil {
+        return 0, fields, nil, err
+    }
+
+    pos := 16
+    end := 16 + int(fieldsLen)
+    for pos < end {
+        pos = (pos + 7) / 8 * 8
+        if pos + 3 > end {
+            break
+        }
+
+        code := msg[pos]
+        sigLen := int(msg[pos + 1])
+        if pos + 3 + sigLen > end {
+            break
+        }
+
+        signature := string(msg[pos + 2:pos + 2 + sigLen])
+        pos += 3 + sigLen
+
+        switch signature {
+        case "u":
+            pos = (pos + 3) / 4 * 4
+            if pos + 4 > end {
+                return 0, fields, nil, errors.New("truncated D-Bus header")
+            }
+
+            if code == dbusFieldReplySerial {
+                fields.replySerial = order.Uint32(msg[pos:])
+            }
+            pos += 4
+        case "s", "o":
+            pos = (pos + 3) / 4 * 4
+            if pos + 4 > end {
+                return 0, fields, nil, errors.New("truncated D-Bus header")
+            }
+
+            strLen := int(order.Uint32(msg[pos:]))
+            if pos + 4 + strLen + 1 > end {
+                return 0, fields, nil, errors.New("truncated D-Bus header")
+            }
+
+            if code == dbusFieldErrorName {
+                fields.errorName = string(msg[pos + 4:pos + 4 + strLen])
+            }
+            pos += 4 + strLen + 1
+        case "g":
+            if pos >= end {
+                return 0, fields, nil, errors.New("truncated D-Bus header")
+            }
+            pos += 1 + int(msg[pos]) + 1
+        default:
+            //Only the fields above are defined, and they are all one of these types
+            return 0, fields, nil, fmt.Errorf("unexpected D-Bus header field type %q", signature)
+        }
+    }
+
+    return msg[1], fields, msg[headerLen:], nil
+}
+
+//dbusErrorMessage returns the message of an error reply, which is its first argument when that's a string
+func dbusErrorMessage(body []byte) string {
+    if len(body) < 4 {
+        return ""
+    }
+
+    length := int(binary.LittleEndian.Uint32(body))
+    if 4 + length > len(body) {
+        return ""
+    }
+
+    return string(body[4:4 + length])
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+    "bufio"
+    "os"
+    "os/exec"
+    "strings"
+    "testing"
+)
+
+func TestDispatchUrl(t *testing.T) {
+    daemon, err := exec.LookPath("dbus-daemon")
+    if err != nil {
+        t.Skip("dbus-daemon isn't installed")
+    }
+
+    cmd := exec.Command(daemon, "--session", "--nofork", "--print-address=1")
+    stdout, err := cmd.StdoutPipe()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if err := cmd.Start(); err != nil {
+        t.Fatal(err)
+    }
+    defer cmd.Process.Kill()
+
+    address, err := bufio.NewReader(stdout).ReadString('\n')
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    defer os.Setenv("DBUS_SESSION_BUS_ADDRESS", os.Getenv("DBUS_SESSION_BUS_ADDRESS"))
+    os.Setenv("DBUS_SESSION_BUS_ADDRESS", strings.TrimSpace(address))
+
+    //The bus answers for the missing url dispatcher, so the whole conversation with it is checked
+    err = dispatchUrl("mailto:")
+    if err == nil || !strings.Contains(err.Error(), "org.freedesktop.DBus.Error.ServiceUnknown") || !strings.Contains(err.Error(), urlDispatcherName) {
+        t.Errorf("expected the bus to report the url dispatcher missing, got %v", err)
+
+    }
+
+    os.Setenv("DBUS_SESSION_BUS_ADDRESS", "tcp:host=localhost")
+    if err := dispatchUrl("mailto:"); err == nil {
+        t.Error("expected an error without a unix socket to connect to")
+    }
+}
@@ -3,23 +3,100 @@ package main
 import (
     "fmt"
     "launchpad.net/go-unityscopes/v2"
-    "log"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
 )
 
 type Falcon struct {
     base *scopes.ScopeBase
-    favFile string
-    favorites []string
+    //Favorites, settings and the app index, which change while queries are running
+    store *Store
+    appDirs []string
+    //Where Libertine keeps its containers of desktop apps
+    libertineDir string
+    //Where click packages are installed, for the details shown in previews
+    clickRoots []string
+    //Where the click packages that can't be uninstalled are
+    preinstalledRoots []string
+    //Where the icons users can give apps are found
+    iconDirs []string
+    remoteScopesFile string
+    cache *scopes.ResultCache
+    translations *Translations
+    //Where Falcon's own translations are installed
+    localeDir string
+    //Opens the uri of a desktop action, the tests replace it
+    launch func(uri string) (*scopes.ActivationResponse, error)
+    //Removes the packages of uninstalled apps
+    packages PackageManager
+    log *Logger
 }
 
-func (falcon *Falcon) Preview(result *scopes.Result, metadata *scopes.ActionMetadata, reply *scopes.PreviewReply, cancelled <-chan bool) error {
-    var app Application
-    if err := result.Get("app", &app); err != nil {
-        log.Println(err)
+func newFalcon() *Falcon {
+    falcon := &Falcon{
+        appDirs: []string{
+            "/usr/share/applications/",
+            "/home/phablet/.local/share/applications/",
+        },
+        remoteScopesFile: "/home/phablet/.cache/unity-scopes/remote-scopes.json",
+        libertineDir: libertineContainerDir(),
+        clickRoots: clickRoots,
+        preinstalledRoots: preinstalledClickRoots,
+        iconDirs: themeIconDirs,
+        store: newStore(),
+        cache: scopes.NewResultCache(time.Minute, 4),
+        launch: dispatchLaunch,
+        packages: clickPackageManager{},
+        log: newLogger(),
     }
+    falcon.translations = newTranslations(falcon.log)
+    //The scope is run from its directory in the click package, which keeps its translations under share/locale
+    falcon.localeDir = filepath.Join(filepath.Dir(os.Args[0]), "..", "share", "locale")
+
+    //Only cache the surfacing results, as they are requested every time the scope is shown
+    falcon.cache.Cacheable = func(query *scopes.CannedQuery, metadata *scopes.SearchMetadata) bool {
+        return query.QueryString() == ""
+    }
+
+    return falcon
+}
+
+func (falcon *Falcon) Preview(result *scopes.Result, metadata *scopes.ActionMetadata, reply scopes.PreviewReplier, cancelled <-chan bool) error {
+    app := falcon.resultApp(result)
+
+    //There is no scope data unless the preview was shown again by an action
+    var data PreviewData
+    metadata.ScopeData(&data)
+
+    return falcon.previewStep(app, data, metadata.Locale(), reply)
+}
+
+//previewStep shows the step of the preview an action asked for, or the app itself
+func (falcon *Falcon) previewStep(app Application, data PreviewData, locale string, reply scopes.PreviewReplier) error {
+    if data.Step == uninstallConfirmStep && falcon.canUninstall(app) {
+        return falcon.previewUninstall(app, locale, reply)
+    } else if data.Step == renameStep {
+        return falcon.previewRename(app, locale, reply)
+    } else if data.Step == chooseIconStep {
+        return falcon.previewChooseIcon(app, data.Page, locale, reply)
+    }
+
+    return falcon.previewApp(app, locale, reply)
+}
+
+func (falcon *Falcon) previewApp(app Application, locale string, reply scopes.PreviewReplier) error {
+    //Both widgets must agree even if the app is favorited while the preview is built
+    state := falcon.store.Snapshot()
+    isFavorite := state.IsFavorite(app.Id)
 
     headerWidget := scopes.NewPreviewWidget("header", "header")
     headerWidget.AddAttributeValue("title", app.Title)
+    if domain := webappDomain(app.WebappUrl); domain != "" {
+        headerWidget.AddAttributeValue("subtitle", domain)
+    }
 
     iconWidget := scopes.NewPreviewWidget("art", "image")
     iconWidget.AddAttributeValue("source", app.Icon)
@@ -28,66 +105,186 @@ func (falcon *Falcon) Preview(result *scopes.Result, metadata *scopes.ActionMeta
     commentWidget.AddAttributeValue("text", app.Comment)
 
     var buttons []ActionInfo
-    buttons = append(buttons, ActionInfo{Id: "launch", Uri: app.Uri, Label: "Launch"})
+    buttons = append(buttons, ActionInfo{Id: "launch", Uri: app.Uri, Label: falcon.tr(locale, "Launch")})
 
-    if falcon.isFavorite(app.Id) {
-        buttons = append(buttons, ActionInfo{Id: "unfavorite", Label: "Unfavorite"})
+    for _, action := range falcon.desktopActions(app, localeLanguages(locale)) {
+        //No uri, or the shell would open it as well as PerformAction
+        buttons = append(buttons, ActionInfo{Id: desktopActionPrefix + action.Id, Label: action.Name, Icon: action.Icon})
+    }
+
+    if isFavorite {
+        buttons = append(buttons, ActionInfo{Id: "unfavorite", Label: falcon.tr(locale, "Unfavorite")})
     } else {
-        buttons = append(buttons, ActionInfo{Id: "favorite", Label: "Favorite"})
+        buttons = append(buttons, ActionInfo{Id: "favorite", Label: falcon.tr(locale, "Favorite")})
+    }
+
+    buttons = append(buttons, ActionInfo{Id: "rename", Label: falcon.tr(locale, "Rename")})
+    buttons = append(buttons, ActionInfo{Id: "change-icon", Label: falcon.tr(locale, "Change Icon")})
+    if state.HasOverride(app.Uri) {
+        buttons = append(buttons, ActionInfo{Id: "reset", Label: falcon.tr(locale, "Reset")})
+    }
+
+    if falcon.canUninstall(app) {
+        buttons = append(buttons, ActionInfo{Id: "uninstall", Label: falcon.tr(locale, "Uninstall")})
     }
 
     actionsWidget := scopes.NewPreviewWidget("actions", "actions")
     actionsWidget.AddAttributeValue("actions", buttons)
 
     messageWidget := scopes.NewPreviewWidget("message", "text")
-    if falcon.isFavorite(app.Id) {
-        messageWidget.AddAttributeValue("text", "Refresh scope to see changes")
+    if isFavorite {
+        messageWidget.AddAttributeValue("text", falcon.tr(locale, "Refresh scope to see changes"))
+    }
+
+    widgets := []scopes.PreviewWidget{headerWidget, iconWidget, commentWidget, actionsWidget, messageWidget}
+
+    click, err := falcon.clickPackage(app.Id)
+    if err != nil {
+        falcon.log.Warn("could not read click package", "app", app.Id, "error", err)
+    }
+
+    if click != nil {
+        widgets = append(widgets, falcon.detailsWidget(click, locale))
+    }
+
+    return reply.PushWidgets(widgets...)
+}
+
+//detailsWidget lists the metadata of an app's click package, collapsed so it doesn't get in the way of launching
+func (falcon *Falcon) detailsWidget(click *ClickPackage, locale string) scopes.PreviewWidget {
+    var values [][]string
+    add := func(label string, value string) {
+        if value != "" {
+            values = append(values, []string{label, value})
+        }
     }
 
-    return reply.PushWidgets(headerWidget, iconWidget, commentWidget, actionsWidget, messageWidget)
+    add(falcon.tr(locale, "Version"), click.Version)
+    add(falcon.tr(locale, "Maintainer"), click.Maintainer)
+    add(falcon.tr(locale, "Framework"), click.Framework)
+    if click.InstalledSize > 0 {
+        add(falcon.tr(locale, "Installed Size"), formatSize(click.InstalledSize))
+    }
+    if !click.Installed.IsZero() {
+        add(falcon.tr(locale, "Installed"), click.Installed.Format("2006-01-02"))
+    }
+    add(falcon.tr(locale, "Permissions"), strings.Join(click.PolicyGroups, ", "))
+
+    tableWidget := scopes.NewPreviewWidget("details-table", "table")
+    tableWidget.AddAttributeValue("values", values)
+
+    detailsWidget := scopes.NewPreviewWidget("details", "expandable")
+    detailsWidget.AddAttributeValue("title", falcon.tr(locale, "Details"))
+    detailsWidget.AddAttributeValue("collapsed-widgets", 0)
+    detailsWidget.AddWidget(tableWidget)
+
+    return detailsWidget
 }
 
-func (falcon *Falcon) Search(query *scopes.CannedQuery, metadata *scopes.SearchMetadata, reply *scopes.SearchReply, cancelled <-chan bool) error {
+func (falcon *Falcon) Search(query *scopes.CannedQuery, metadata *scopes.SearchMetadata, reply scopes.SearchReplier, cancelled <-chan bool) error {
     q := query.QueryString()
-    log.Println(fmt.Sprintf("query: %s", q))
 
-    if falcon.favFile == "" {
-        falcon.favFile = fmt.Sprintf("%s/favorites.txt", falcon.base.CacheDirectory())
-        falcon.loadFavorites()
+    if err := falcon.addApps(q, metadata.Locale(), reply, cancelled); err != nil {
+        return err
     }
 
-    if err := falcon.addApps(q, reply); err != nil {
-        log.Fatalln(err)
+    if isCancelled(cancelled) {
+        falcon.log.Info("query cancelled", "query", q)
     }
 
     return nil
 }
 
 func (falcon *Falcon) PerformAction(result *scopes.Result, metadata *scopes.ActionMetadata, widgetId, actionId string) (*scopes.ActivationResponse, error) {
+    app := falcon.resultApp(result)
+
+    //The new title is typed into the rename widget rather than chosen with a button
+    if widgetId == renameStep {
+        var input struct {
+            Comment string `json:"comment"`
+        }
+        metadata.ScopeData(&input)
+
+        if err := falcon.renameApp(app, input.Comment); err != nil {
+            return nil, err
+        }
+
+        return scopes.NewActivationResponse(scopes.ActivationShowPreview), nil
+    }
+
+    return falcon.performAppAction(app, actionId)
+}
+
+func (falcon *Falcon) performAppAction(app Application, actionId string) (*scopes.ActivationResponse, error) {
     var resp *scopes.ActivationResponse
 
     if actionId == "favorite" {
-        var app Application
-        if err := result.Get("app", &app); err != nil {
-            log.Println(err)
+        if app.Id != "" {
+            if err := falcon.favorite(app.Id); err != nil {
+                return nil, err
+            }
         }
 
+        resp = scopes.NewActivationResponse(scopes.ActivationShowPreview)
+    } else if actionId == "unfavorite" {
         if app.Id != "" {
-            falcon.favorite(app.Id)
+            if err := falcon.unfavorite(app.Id); err != nil {
+                return nil, err
+            }
         }
 
         resp = scopes.NewActivationResponse(scopes.ActivationShowPreview)
-    } else if actionId == "unfavorite" {
-        var app Application
-        if err := result.Get("app", &app); err != nil {
-            log.Println(err)
+    } else if strings.HasPrefix(actionId, desktopActionPrefix) {
+        launched, err := falcon.launchDesktopAction(app, strings.TrimPrefix(actionId, desktopActionPrefix))
+        if err != nil {
+            return nil, err
         }
 
-        if app.Id != "" {
-            falcon.unfavorite(app.Id)
+        resp = launched
+    } else if actionId == "rename" || actionId == "change-icon" {
+        step := renameStep
+        if actionId == "change-icon" {
+            step = chooseIconStep
         }
 
         resp = scopes.NewActivationResponse(scopes.ActivationShowPreview)
+        resp.SetScopeData(PreviewData{Step: step})
+    } else if strings.HasPrefix(actionId, iconPagePrefix) {
+        page, err := strconv.Atoi(strings.TrimPrefix(actionId, iconPagePrefix))
+        if err != nil {
+            return nil, fmt.Errorf("invalid page of icons %q", actionId)
+        }
+
+        resp = scopes.NewActivationResponse(scopes.ActivationShowPreview)
+        resp.SetScopeData(PreviewData{Step: chooseIconStep, Page: page})
+    } else if strings.HasPrefix(actionId, overrideIconPrefix) {
+        if err := falcon.setAppIcon(app, strings.TrimPrefix(actionId, overrideIconPrefix)); err != nil {
+            return nil, err
+        }
+
+        resp = scopes.NewActivationResponse(scopes.ActivationShowPreview)
+    } else if actionId == "reset" {
+        if err := falcon.resetApp(app); err != nil {
+            return nil, err
+        }
+
+        resp = scopes.NewActivationResponse(scopes.ActivationShowPreview)
+    } else if actionId == "uninstall" {
+        if !falcon.canUninstall(app) {
+            return nil, fmt.Errorf("%s can't be uninstalled", app.Uri)
+        }
+
+        resp = scopes.NewActivationResponse(scopes.ActivationShowPreview)
+        resp.SetScopeData(PreviewData{Step: uninstallConfirmStep})
+    } else if actionId == "uninstall-confirm" {
+        uninstalled, err := falcon.uninstall(app)
+        if err != nil {
+            return nil, err
+        }
+
+        resp = uninstalled
+    } else if actionId == "uninstall-cancel" {
+        resp = scopes.NewActivationResponse(scopes.ActivationShowPreview)
     } else {
         resp = scopes.NewActivationResponse(scopes.ActivationNotHandled)
     }
@@ -96,11 +293,13 @@ func (falcon *Falcon) PerformAction(result *scopes.Result, metadata *scopes.Acti
 }
 
 func (falcon *Falcon) Activate(result *scopes.Result, metadata *scopes.ActionMetadata) (*scopes.ActivationResponse, error) {
+    app := falcon.resultApp(result)
+
+    return falcon.activateApp(app), nil
+}
+
+func (falcon *Falcon) activateApp(app Application) *scopes.ActivationResponse {
     var resp *scopes.ActivationResponse
-    var app Application
-    if err := result.Get("app", &app); err != nil {
-        log.Println(err)
-    }
 
     if app.IsApp {
         //Let the uri handler open the app
@@ -111,18 +310,17 @@ func (falcon *Falcon) Activate(result *scopes.Result, metadata *scopes.ActionMet
         resp = scopes.NewActivationResponseForQuery(query)
     }
 
-    return resp, nil
+    return resp
 }
 
 func (falcon *Falcon) SetScopeBase(base *scopes.ScopeBase) {
     falcon.base = base
-}
-
-func main() {
-    log.Println("starting up")
 
-    scope := &Falcon{}
-    if err := scopes.Run(scope); err != nil {
-        log.Fatalln(err)
+    if base != nil {
+        falcon.localeDir = filepath.Join(base.ScopeDirectory(), "..", "share", "locale")
+        falcon.log.SetFile(filepath.Join(base.CacheDirectory(), "falcon.log"))
+        falcon.loadSettings()
+        falcon.loadFavorites(filepath.Join(base.CacheDirectory(), "favorites.txt"))
+        falcon.loadOverrides(filepath.Join(base.CacheDirectory(), "overrides.json"))
     }
 }
@@ -1,55 +1,83 @@
 package main
 
 import (
+    "errors"
     "io/ioutil"
-    "log"
+    "os"
     "strings"
 )
 
-func (falcon *Falcon) favorite(appId string) {
-    falcon.favorites = append(falcon.favorites, appId)
-
-    falcon.saveFavorites()
+func (falcon *Falcon) favorite(appId string) error {
+    return falcon.updateFavorites(func(favorites []string) []string {
+        //Copy so readers of the current snapshot don't see the append
+        return append(append([]string{}, favorites...), appId)
+    })
 }
 
-func (falcon *Falcon) unfavorite(appId string) {
-    var newFavorites []string
+func (falcon *Falcon) unfavorite(appId string) error {
+    return falcon.updateFavorites(func(favorites []string) []string {
+        var newFavorites []string
 
-    for _, id := range falcon.favorites {
-        if id != appId {
-            newFavorites = append(newFavorites, id)
+        for _, id := range favorites {
+            if id != appId {
+                newFavorites = append(newFavorites, id)
+            }
         }
-    }
 
-    falcon.favorites = newFavorites
-    falcon.saveFavorites()
+        return newFavorites
+    })
 }
 
-func (falcon *Falcon) saveFavorites() {
-    data := []byte(strings.Join(falcon.favorites, "\n"))
-    if err := ioutil.WriteFile(falcon.favFile, data, 0777); err != nil {
-        log.Fatalln(err)
-    }
+//The favorites only change once they have been saved, so Falcon never shows favorites that will be lost on restart
+func (falcon *Falcon) updateFavorites(update func(favorites []string) []string) error {
+    err := falcon.store.Update(func(state *State) error {
+        if state.FavFile == "" {
+            return errors.New("no favorites file to save to")
+        }
+
+        favorites := update(state.Favorites)
+        if err := saveFavorites(state.FavFile, favorites); err != nil {
+            falcon.log.Error("could not save favorites", "file", state.FavFile, "error", err)
+            return err
+        }
+
+        state.Favorites = favorites
+        return nil
+    })
+
+    //The cached results show the old favorites
+    falcon.cache.Purge()
+
+    return err
 }
 
-func (falcon *Falcon) loadFavorites() {
-    content, err := ioutil.ReadFile(falcon.favFile)
-    if err != nil {
-        log.Fatalln(err)
-    } else {
-        falcon.favorites = strings.Split(string(content), "\n")
-    }
+func saveFavorites(file string, favorites []string) error {
+    data := []byte(strings.Join(favorites, "\n"))
+
+    return ioutil.WriteFile(file, data, 0777)
 }
 
-func (falcon *Falcon) isFavorite(appId string) bool {
-    var isFav bool = false
+//loadFavorites reads the favorites from file, which is where they will be saved from now on
+func (falcon *Falcon) loadFavorites(file string) {
+    var favorites []string
 
-    for _, id := range falcon.favorites {
-        if id == appId {
-            isFav = true
-            break
+    content, err := ioutil.ReadFile(file)
+    if err != nil {
+        //There is no favorites file until the first app is favorited
+        if os.IsNotExist(err) {
+            falcon.log.Info("no favorites saved yet", "file", file)
+        } else {
+            falcon.log.Warn("could not load favorites", "file", file, "error", err)
         }
+    } else {
+        favorites = strings.Split(string(content), "\n")
     }
 
-    return isFav
+    falcon.store.Update(func(state *State) error {
+        state.FavFile = file
+        state.Favorites = favorites
+        return nil
+    })
+
+    falcon.cache.Purge()
 }
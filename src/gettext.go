@@ -0,0 +1,198 @@
+package main
+
+import (
+    "bytes"
+    "encoding/binary"
+    "errors"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+)
+
+//Where the system's translations are installed, used when an app doesn't ship its own
+const systemLocaleDir = "/usr/share/locale"
+
+//Click packages are installed under these directories, /opt/click.ubuntu.com has a directory per version of each package
+var clickRoots = append([]string{"/opt/click.ubuntu.com/"}, preinstalledClickRoots...)
+
+//localeLanguages returns the languages to look up for a locale, de_DE.UTF-8@euro is looked up as de_DE and then de
+func localeLanguages(locale string) []string {
+    locale = strings.SplitN(locale, "@", 2)[0]
+    locale = strings.SplitN(locale, ".", 2)[0]
+    if locale == "" || locale == "C" || locale == "POSIX" {
+        return nil
+    }
+
+    langs := []string{locale}
+    if short := strings.SplitN(locale, "_", 2)[0]; short != locale {
+        langs = append(langs, short)
+    }
+
+    return langs
+}
+
+//appInstallDir finds the directory an app's package is installed in, which is where click apps keep their translations
+func appInstallDir(desktopMap map[string]string) string {
+    if path, ok := desktopMap["path"]; ok && path != "" {
+        return path
+    }
+
+    icon := desktopMap["icon"]
+    for _, root := range clickRoots {
+        if !strings.HasPrefix(icon, root) {
+            continue
+        }
+
+        parts := strings.Split(strings.TrimPrefix(icon, root), "/")
+        depth := 1
+        if root == clickRoots[0] {
+            depth = 2
+        }
+
+        if len(parts) <= depth {
+            return ""
+        }
+
+        return filepath.Join(root, filepath.Join(parts[:depth]...))
+    }
+
+    return ""
+}
+
+//appLocaleDirs returns the directories to look for an app's translations in, in order
+func appLocaleDirs(desktopMap map[string]string) []string {
+    var dirs []string
+    if dir := appInstallDir(desktopMap); dir != "" {
+        dirs = append(dirs, filepath.Join(dir, "share", "locale"))
+    }
+
+    return append(dirs, systemLocaleDir)
+}
+
+type catalog struct {
+    modTime time.Time
+    messages map[string]string
+}
+
+//Translations looks up messages in gettext .mo files without touching the process' locale, so it is safe to use from
+//concurrent searches. Catalogs are cached by file, and reloaded when the file changes
+type Translations struct {
+    lock sync.Mutex
+    catalogs map[string]*catalog
+    log *Logger
+}
+
+func newTranslations(log *Logger) *Translations {
+    return &Translations{
+        catalogs: map[string]*catalog{},
+        log: log,
+    }
+}
+
+//Translate returns the translation of msgid in the first of localeDirs and langs that has one, or msgid if none do
+func (translations *Translations) Translate(localeDirs []string, domain string, langs []string, msgid string) string {
+    for _, lang := range langs {
+        for _, dir := range localeDirs {
+            file := filepath.Join(dir, lang, "LC_MESSAGES", domain + ".mo")
+            if translation, ok := translations.catalog(file)[msgid]; ok && translation != "" {
+                return translation
+            }
+        }
+    }
+
+    return msgid
+}
+
+func (translations *Translations) catalog(file string) map[string]string {
+    //Most apps don't have a translation for every language, so missing files are expected
+    info, err := os.Stat(file)
+    if err != nil {
+        return nil
+    }
+
+    translations.lock.Lock()
+    defer translations.lock.Unlock()
+
+    if cached, ok := translations.catalogs[file]; ok && cached.modTime.Equal(info.ModTime()) {
+        return cached.messages
+    }
+
+    //A broken catalog is cached as empty so it isn't parsed again until it changes
+    cached := &catalog{modTime: info.ModTime()}
+    data, err := ioutil.ReadFile(file)
+    if err == nil {
+        cached.messages, err = parseMo(data)
+    }
+
+    if err != nil {
+        translations.log.Warn("could not load translations", "file", file, "error", err)
+    }
+
+    translations.catalogs[file] = cached
+    return cached.messages
+}
+
+//parseMo reads the messages from a gettext .mo file. Only the singular form of each message is kept
+func parseMo(data []byte) (map[string]string, error) {
+    if len(data) < 20 {
+        return nil, errors.New("mo file is too short")
+    }
+
+    var order binary.ByteOrder
+    switch binary.LittleEndian.Uint32(data) {
+    case 0x950412de:
+        order = binary.LittleEndian
+    case 0xde120495:
+        order = binary.BigEndian
+    default:
+        return nil, errors.New("not a mo file")
+    }
+
+    count := order.Uint32(data[8:])
+    originals := order.Uint32(data[12:])
+    translated := order.Uint32(data[16:])
+
+    str := func(table uint32, i uint32) (string, error) {
+        entry := uint64(table) + uint64(i) * 8
+        if entry + 8 > uint64(len(data)) {
+            return "", errors.New("mo file is truncated")
+        }
+
+        length := uint64(order.Uint32(data[entry:]))
+        offset := uint64(order.Uint32(data[entry + 4:]))
+        if offset + length > uint64(len(data)) {
+            return "", errors.New("mo file is truncated")
+        }
+
+        //Plural forms follow the singular, separated by NUL
+        value := data[offset:offset + length]
+        if i := bytes.IndexByte(value, 0); i >= 0 {
+            value = value[:i]
+        }
+
+        return string(value), nil
+    }
+
+    messages := map[string]string{}
+    for i := uint32(0); i < count; i++ {
+        msgid, err := str(originals, i)
+        if err != nil {
+            return nil, err
+        }
+
+        msgstr, err := str(translated, i)
+        if err != nil {
+            return nil, err
+        }
+
+        //The empty msgid holds the catalog's metadata
+        if msgid != "" {
+            messages[msgid] = msgstr
+        }
+    }
+
+    return messages, nil
+}
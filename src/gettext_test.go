@@ -0,0 +1,157 @@
+package main
+
+import (
+    "bytes"
+    "encoding/binary"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "reflect"
+    "sort"
+    "testing"
+    "time"
+)
+
+//encodeMo builds a little endian .mo file with the given messages
+func encodeMo(messages map[string]string) []byte {
+    var ids []string
+    for id := range messages {
+        ids = append(ids, id)
+    }
+    sort.Strings(ids)
+
+    count := uint32(len(ids))
+    originals := uint32(28)
+    translated := originals + count * 8
+    offset := translated + count * 8
+
+    var header, strs bytes.Buffer
+    for _, v := range []uint32{0x950412de, 0, count, originals, translated, 0, 0} {
+        binary.Write(&header, binary.LittleEndian, v)
+    }
+
+    var table [2][]uint32
+    for i, values := range [2]func(string) string{
+        func(id string) string { return id },
+        func(id string) string { return messages[id] },
+    } {
+        for _, id := range ids {
+            value := values(id)
+            table[i] = append(table[i], uint32(len(value)), offset + uint32(strs.Len()))
+            strs.WriteString(value)
+            strs.WriteByte(0)
+        }
+    }
+
+    binary.Write(&header, binary.LittleEndian, table[0])
+    binary.Write(&header, binary.LittleEndian, table[1])
+    return append(header.Bytes(), strs.Bytes()...)
+}
+
+func TestParseMo(t *testing.T) {
+    messages, err := parseMo(encodeMo(map[string]string{
+        "": "Content-Type: text/plain; charset=UTF-8\n",
+        "Calculator": "Taschenrechner",
+        "%d app\x00%d apps": "%d App\x00%d Apps",
+    }))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    expected := map[string]string{"Calculator": "Taschenrechner", "%d app": "%d App"}
+    if !reflect.DeepEqual(messages, expected) {
+        t.Errorf("expected %v, got %v", expected, messages)
+    }
+
+    for _, data := range [][]byte{nil, []byte("not a mo file at all"), encodeMo(map[string]string{"a": "b"})[:40]} {
+        if _, err := parseMo(data); err == nil {
+            t.Errorf("expected an error parsing %q", data)
+        }
+    }
+}
+
+func TestLocaleLanguages(t *testing.T) {
+    cases := map[string][]string{
+        "de_DE.UTF-8": {"de_DE", "de"},
+        "fr_FR@euro": {"fr_FR", "fr"},
+        "pt": {"pt"},
+        "C.UTF-8": nil,
+        "": nil,
+    }
+
+    for locale, expected := range cases {
+        if langs := localeLanguages(locale); !reflect.DeepEqual(langs, expected) {
+            t.Errorf("expected %v for %q, got %v", expected, locale, langs)
+        }
+    }
+}
+
+func TestAppLocaleDirs(t *testing.T) {
+    cases := []struct {
+        desktop map[string]string
+        dirs []string
+    }{
+        {map[string]string{"path": "/opt/click.ubuntu.com/.click/users/phablet/com.ubuntu.calculator"}, []string{"/opt/click.ubuntu.com/.click/users/phablet/com.ubuntu.calculator/share/locale", systemLocaleDir}},
+        {map[string]string{"icon": "/opt/click.ubuntu.com/com.ubuntu.terminal/0.7/terminal.png"}, []string{"/opt/click.ubuntu.com/com.ubuntu.terminal/0.7/share/locale", systemLocaleDir}},
+        {map[string]string{"icon": "/usr/share/click/preinstalled/com.ubuntu.calculator/calculator.svg"}, []string{"/usr/share/click/preinstalled/com.ubuntu.calculator/share/locale", systemLocaleDir}},
+        {map[string]string{"icon": "/usr/share/icons/suru/apps/128/system-settings.png"}, []string{systemLocaleDir}},
+    }
+
+    for _, c := range cases {
+        if dirs := appLocaleDirs(c.desktop); !reflect.DeepEqual(dirs, c.dirs) {
+            t.Errorf("expected %v for %v, got %v", c.dirs, c.desktop, dirs)
+        }
+    }
+}
+
+func TestTranslate(t *testing.T) {
+    dir, err := ioutil.TempDir("", "falcon-locale")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+
+    appDir := filepath.Join(dir, "app")
+    systemDir := filepath.Join(dir, "system")
+    write := func(localeDir, lang string, messages map[string]string) string {
+        file := filepath.Join(localeDir, lang, "LC_MESSAGES", "calculator.mo")
+        if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+            t.Fatal(err)
+        }
+
+        if err := ioutil.WriteFile(file, encodeMo(messages), 0644); err != nil {
+            t.Fatal(err)
+        }
+
+        return file
+    }
+
+    write(systemDir, "de", map[string]string{"Calculator": "Rechner"})
+    file := write(appDir, "de_AT", map[string]string{"Calculator": "Taschenrechner"})
+
+    translations := newTranslations(newLogger())
+    dirs := []string{appDir, systemDir}
+
+    if title := translations.Translate(dirs, "calculator", []string{"de_AT", "de"}, "Calculator"); title != "Taschenrechner" {
+        t.Errorf("expected the app's own translation, got %q", title)
+    }
+
+    if title := translations.Translate(dirs, "calculator", []string{"de"}, "Calculator"); title != "Rechner" {
+        t.Errorf("expected the system translation, got %q", title)
+    }
+
+    if title := translations.Translate(dirs, "calculator", []string{"fr"}, "Calculator"); title != "Calculator" {
+        t.Errorf("expected the untranslated message, got %q", title)
+    }
+
+    //An updated app's catalog replaces the cached one
+    write(appDir, "de_AT", map[string]string{"Calculator": "Rechenmaschine"})
+    later := time.Now().Add(time.Minute)
+    if err := os.Chtimes(file, later, later); err != nil {
+        t.Fatal(err)
+    }
+
+    if title := translations.Translate(dirs, "calculator", []string{"de_AT"}, "Calculator"); title != "Rechenmaschine" {
+        t.Errorf("expected the updated translation, got %q", title)
+    }
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "sort"
+)
+
+//Falcon's own strings are in the gettext domain named after its click package.
+//They are extracted from the calls to tr by `gulp pot`, so tr must always be given a string literal
+const textDomain = "falcon.bhdouglass"
+
+//tr translates one of Falcon's own strings to the locale of a query, such as de_DE
+func (falcon *Falcon) tr(locale string, msgid string) string {
+    return falcon.translations.Translate([]string{falcon.localeDir}, textDomain, localeLanguages(locale), msgid)
+}
+
+//localeCatalogs returns the languages Falcon has been translated to
+func (falcon *Falcon) localeCatalogs() []string {
+    dirs, err := ioutil.ReadDir(falcon.localeDir)
+    if err != nil {
+        if !os.IsNotExist(err) {
+            falcon.log.Warn("could not read translations", "dir", falcon.localeDir, "error", err)
+        }
+
+        return nil
+    }
+
+    var langs []string
+    for _, dir := range dirs {
+        if _, err := os.Stat(filepath.Join(falcon.localeDir, dir.Name(), "LC_MESSAGES", textDomain + ".mo")); err == nil {
+            langs = append(langs, dir.Name())
+        }
+    }
+
+    sort.Strings(langs)
+    return langs
+}
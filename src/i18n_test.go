@@ -0,0 +1,91 @@
+package main
+
+import (
+    "bytes"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestLocalizedStrings(t *testing.T) {
+    dir, err := ioutil.TempDir("", "falcon-i18n")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+
+    file := filepath.Join(dir, "de", "LC_MESSAGES", textDomain + ".mo")
+    if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+        t.Fatal(err)
+    }
+
+    if err := ioutil.WriteFile(file, encodeMo(map[string]string{
+        "Favorites": "Favoriten",
+        "Layout": "Anordnung",
+        "Group Apps & Scopes": "Apps & Scopes gruppieren",
+        "Group by First Letter": "Nach Anfangsbuchstaben gruppieren",
+    }), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    falcon := newFalcon()
+    falcon.localeDir = dir
+
+    cases := map[string]string{
+        "de_DE": "Favoriten",
+        "de": "Favoriten",
+        "fr_FR": "Favorites",
+        "": "Favorites",
+    }
+    for locale, expected := range cases {
+        if title := falcon.tr(locale, "Favorites"); title != expected {
+            t.Errorf("expected %q for %q, got %q", expected, locale, title)
+        }
+    }
+
+    var buf bytes.Buffer
+    if err := falcon.SettingsDefinition().WriteIni(&buf); err != nil {
+        t.Fatal(err)
+    }
+
+    for _, line := range []string{
+        "displayName = Layout\n",
+        "displayName[de] = Anordnung\n",
+        "displayValues = Group Apps & Scopes;Group by First Letter;Group by Category\n",
+        "displayValues[de] = Apps & Scopes gruppieren;Nach Anfangsbuchstaben gruppieren;Group by Category\n",
+    } {
+        if !strings.Contains(buf.String(), line) {
+            t.Errorf("expected %q in settings:\n%s", line, buf.String())
+        }
+    }
+}
+
+func TestAppTitlesFollowRequestLocale(t *testing.T) {
+    dir, err := ioutil.TempDir("", "falcon-i18n")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+
+    desktop := "[Desktop Entry]\nName=Notes\nName[de]=Notizen\nName[fr]=Bloc-notes\nExec=notes\nType=Application\nX-Ubuntu-Touch=true\n"
+    if err := ioutil.WriteFile(filepath.Join(dir, "notes.desktop"), []byte(desktop), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    //The scope's environment has a different language than the request
+    defer os.Setenv("LANGUAGE", os.Getenv("LANGUAGE"))
+    defer os.Setenv("LANG", os.Getenv("LANG"))
+    os.Setenv("LANGUAGE", "")
+    os.Setenv("LANG", "fr_FR.UTF-8")
+
+    falcon := newTestFalcon(layoutFirstLetter)
+    falcon.appDirs = []string{dir}
+
+    for locale, expected := range map[string]string{"de_DE": "Notizen", "C": "Notes", "": "Notes"} {
+        if titles := searchTitles(t, falcon, "not", locale); len(titles) != 1 || titles[0] != expected {
+            t.Errorf("expected %q for %q, got %q", expected, locale, titles)
+        }
+    }
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+    "fmt"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+//Libertine installs desktop apps in containers, each with its own root filesystem
+const libertineApplicationDir = "usr/share/applications"
+
+//Where icons named in a desktop file are looked for inside a container, most preferred first
+var libertineIconDirs = []string{
+    "usr/share/icons/hicolor/scalable/apps",
+    "usr/share/icons/hicolor/256x256/apps",
+    "usr/share/icons/hicolor/128x128/apps",
+    "usr/share/icons/hicolor/64x64/apps",
+    "usr/share/icons/hicolor/48x48/apps",
+    "usr/share/pixmaps",
+}
+
+//Some desktop files name the icon's file rather than the icon
+var libertineIconExtensions = []string{".svg", ".png", ".xpm", ""}
+
+//libertineContainerDir returns where Libertine keeps the containers of the user running the scope, in their cache
+//directory
+func libertineContainerDir() string {
+    cache := os.Getenv("XDG_CACHE_HOME")
+    if cache == "" {
+        home := os.Getenv("HOME")
+        if home == "" {
+            return ""
+        }
+
+        cache = filepath.Join(home, ".cache")
+    }
+
+    return filepath.Join(cache, "libertine-container")
+}
+
+//libertineApps returns the apps installed in every Libertine container
+func (falcon *Falcon) libertineApps(langs []string, cancelled <-chan bool) []Application {
+    if falcon.libertineDir == "" {
+        return nil
+    }
+
+    containers, err := ioutil.ReadDir(falcon.libertineDir)
+    if err != nil {
+        //Most devices have no containers
+        if !os.IsNotExist(err) {
+            falcon.log.Warn("could not read libertine containers", "dir", falcon.libertineDir, "error", err)
+        }
+
+        return nil
+    }
+
+    var apps []Application
+    for _, container := range containers {
+        if !container.IsDir() {
+            continue
+        }
+
+        rootfs := filepath.Join(falcon.libertineDir, container.Name(), "rootfs")
+        dir := filepath.Join(rootfs, libertineApplicationDir)
+        files, err := ioutil.ReadDir(dir)
+        if err != nil {
+            falcon.log.Debug("skipping libertine container without apps", "container", container.Name(), "error", err)
+            continue
+        }
+
+        for _, f := range files {
+            if isCancelled(cancelled) {
+                return nil
+            }
+
+            if !strings.HasSuffix(f.Name(), ".desktop") {
+                continue
+            }
+
+            content, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+            if err != nil {
+                falcon.log.Warn("skipping unreadable desktop file", "file", filepath.Join(dir, f.Name()), "error", err)
+                continue
+            }
+
+            if app, ok := falcon.libertineApp(container.Name(), rootfs, f.Name(), string(content), langs); ok {
+                apps = append(apps, app)
+            }
+        }
+    }
+
+    return apps
+}
+
+//libertineApp reads a desktop file from a container, returning false if the app shouldn't be shown
+func (falcon *Falcon) libertineApp(container string, rootfs string, file string, content string, langs []string) (Application, bool) {
+    desktopMap := parseDesktopFile(content)[desktopEntryGroup]
+    if desktopMap == nil || desktopMap["name"] == "" {
+        return Application{}, false
+    }
+
+    //Terminal apps can't be shown on the phone, and apps hidden from the menu aren't meant to be launched directly
+    for _, key := range []string{"nodisplay", "hidden", "terminal"} {
+        if strings.ToLower(desktopMap[key]) == "true" {
+            return Application{}, false
+        }
+    }
+
+    if value, ok := desktopMap["type"]; ok && value != "Application" {
+        return Application{}, false
+    }
+
+    name := strings.TrimSuffix(file, ".desktop")
+
+    var app Application
+    app.Id = fmt.Sprintf("%s_%s_0.0", container, name)
+    //The url dispatcher launches the app in its container with libertine-launch
+    app.Uri = fmt.Sprintf("appid://%s/%s/0.0", container, name)
+    app.Title = falcon.localizedName(desktopMap, desktopMap, langs)
+    app.Sort = strings.ToLower(app.Title)
+    app.Comment = desktopMap["comment"]
+    app.Icon = libertineIcon(rootfs, desktopMap["icon"])
+    app.Desktop = content
+    app.IsApp = true
+    if value, ok := desktopMap["categories"]; ok {
+        app.Category = mainCategory(value)
+    }
+    app.Container = container
+
+    return app, true
+}
+
+//libertineIcon finds the file of an icon inside a container
+func libertineIcon(rootfs string, icon string) string {
+    if icon != "" && icon[0:1] == "/" {
+        if _, err := os.Stat(filepath.Join(rootfs, icon)); err == nil {
+            return "file://" + filepath.Join(rootfs, icon)
+        }
+    } else if icon != "" {
+        for _, dir := range libertineIconDirs {
+            for _, ext := range libertineIconExtensions {
+                file := filepath.Join(rootfs, dir, icon + ext)
+                if _, err := os.Stat(file); err == nil {
+                    return "file://" + file
+                }
+            }
+        }
+    }
+
+    return "file:///usr/share/icons/suru/apps/128/placeholder-app-icon.png"
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+    "encoding/json"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "reflect"
+    "sort"
+    "testing"
+)
+
+func TestLibertineApps(t *testing.T) {
+    falcon := newFalcon()
+    falcon.libertineDir = filepath.Join("testdata", "libertine")
+
+    apps := Applications(falcon.libertineApps([]string{"de"}, nil))
+    sort.Sort(apps)
+
+    rootfs := filepath.Join("testdata", "libertine", "xenial", "rootfs")
+    desktop := func(name string) string {
+        content, err := ioutil.ReadFile(filepath.Join(rootfs, "usr", "share", "applications", name))
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        return string(content)
+    }
+
+    expected := Applications{
+        {
+            Id: "xenial_gimp_0.0",
+            Title: "GNU-Bildbearbeitungsprogramm",
+            Comment: "Create images and edit photographs",
+            Icon: "file://" + filepath.Join(rootfs, "usr", "share", "icons", "hicolor", "48x48", "apps", "gimp.png"),
+            Uri: "appid://xenial/gimp/0.0",
+            Desktop: desktop("gimp.desktop"),
+            IsApp: true,
+            Sort: "gnu-bildbearbeitungsprogramm",
+            Category: "Graphics",
+            Container: "xenial",
+        },
+        {
+            Id: "xenial_gedit_0.0",
+            Title: "Text Editor",
+            Icon: "file://" + filepath.Join(rootfs, "usr", "share", "pixmaps", "gedit.xpm"),
+            Uri: "appid://xenial/gedit/0.0",
+            Desktop: desktop("gedit.desktop"),
+            IsApp: true,
+            Sort: "text editor",
+            Container: "xenial",
+        },
+    }
+
+    //The terminal app and the files that aren't desktop files are skipped
+    if len(apps) != len(expected) {
+        t.Fatalf("expected %d apps, got %+v", len(expected), apps)
+    }
+
+    for i := range expected {
+        if !reflect.DeepEqual(apps[i], expected[i]) {
+            t.Errorf("expected %+v, got %+v", expected[i], apps[i])
+        }
+    }
+
+    //Their commands only exist in the container
+    if actions := falcon.desktopActions(apps[0], nil); len(actions) != 0 {
+        t.Errorf("expected no quick actions for a desktop app, got %+v", actions)
+    }
+
+    falcon.libertineDir = filepath.Join("testdata", "missing")
+    if apps := falcon.libertineApps(nil, nil); len(apps) != 0 {
+        t.Errorf("expected no apps without containers, got %+v", apps)
+    }
+}
+
+func TestLibertineContainerDir(t *testing.T) {
+    defer os.Setenv("HOME", os.Getenv("HOME"))
+    defer os.Setenv("XDG_CACHE_HOME", os.Getenv("XDG_CACHE_HOME"))
+
+    os.Setenv("HOME", "/home/jane")
+    os.Setenv("XDG_CACHE_HOME", "")
+    if dir := libertineContainerDir(); dir != "/home/jane/.cache/libertine-container" {
+        t.Errorf("expected the containers in the home directory's cache, got %q", dir)
+    }
+
+    os.Setenv("XDG_CACHE_HOME", "/var/cache/jane")
+    if dir := libertineContainerDir(); dir != "/var/cache/jane/libertine-container" {
+        t.Errorf("expected the containers in XDG_CACHE_HOME, got %q", dir)
+    }
+
+    os.Setenv("XDG_CACHE_HOME", "")
+    os.Setenv("HOME", "")
+    if dir := libertineContainerDir(); dir != "" {
+        t.Errorf("expected no containers without a home directory, got %q", dir)
+    }
+}
+
+func TestLibertineAppsCategory(t *testing.T) {
+    falcon := newTestFalcon(layoutAppsScopes)
+    falcon.libertineDir = filepath.Join("testdata", "libertine")
+
+    _, replayed := replayFile(t, falcon, filepath.Join("testdata", "layout-apps-scopes.jsonl"))
+
+    found := map[string]bool{}
+    for _, entry := range replayed {
+        if entry.Request != 1 || entry.Kind != "result" {
+            continue
+        }
+
+        var result struct {
+            Category string `json:"category"`
+            Attrs struct {
+                Uri string `json:"uri"`
+            } `json:"attrs"`
+        }
+        if err := json.Unmarshal(entry.Data, &result); err != nil {
+            t.Fatal(err)
+        }
+
+        if result.Category == "desktop-apps" {
+            found[result.Attrs.Uri] = true
+        }
+    }
+
+    if !found["appid://xenial/gimp/0.0"] || !found["appid://xenial/gedit/0.0"] || len(found) != 2 {
+        t.Errorf("expected the desktop apps in their own category, got %v", found)
+    }
+}
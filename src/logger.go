@@ -0,0 +1,226 @@
+package main
+
+import (
+    "fmt"
+    "io"
+    "os"
+    "strings"
+    "sync"
+    "time"
+)
+
+const (
+    levelDebug = iota
+    levelInfo
+    levelWarn
+    levelError
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+const (
+    //The log file is rotated once it grows past this size, keeping logBackups old files
+    logMaxSize = 512 * 1024
+    logBackups = 2
+)
+
+//Logger writes levelled logfmt lines to stderr and, once the cache directory is known, to a rotated log file
+type Logger struct {
+    lock sync.Mutex
+    level int
+    stderr io.Writer
+    file *rotatingFile
+    now func() time.Time
+}
+
+func newLogger() *Logger {
+    logger := &Logger{
+        level: levelInfo,
+        stderr: os.Stderr,
+        now: time.Now,
+    }
+
+    if level := os.Getenv("FALCON_LOG_LEVEL"); level != "" {
+        logger.SetLevel(level)
+    }
+
+    return logger
+}
+
+func (logger *Logger) SetLevel(name string) {
+    logger.lock.Lock()
+    defer logger.lock.Unlock()
+
+    for level, levelName := range levelNames {
+        if strings.ToLower(name) == levelName {
+            logger.level = level
+        }
+    }
+}
+
+//SetFile starts writing to the given log file as well as stderr
+func (logger *Logger) SetFile(path string) {
+    logger.lock.Lock()
+    defer logger.lock.Unlock()
+
+    if logger.file != nil {
+        logger.file.Close()
+    }
+
+    logger.file = &rotatingFile{path: path, maxSize: logMaxSize, backups: logBackups}
+}
+
+func (logger *Logger) Debug(msg string, keyvals ...interface{}) {
+    logger.log(levelDebug, msg, keyvals...)
+}
+
+func (logger *Logger) Info(msg string, keyvals ...interface{}) {
+    logger.log(levelInfo, msg, keyvals...)
+}
+
+func (logger *Logger) Warn(msg string, keyvals ...interface{}) {
+    logger.log(levelWarn, msg, keyvals...)
+}
+
+func (logger *Logger) Error(msg string, keyvals ...interface{}) {
+    logger.log(levelError, msg, keyvals...)
+}
+
+//Writer returns a writer that logs each line written to it at the given level, for use with log.New
+func (logger *Logger) Writer(level int) io.Writer {
+    return &logWriter{logger: logger, level: level}
+}
+
+func (logger *Logger) log(level int, msg string, keyvals ...interface{}) {
+    logger.lock.Lock()
+    defer logger.lock.Unlock()
+
+    if level < logger.level {
+        return
+    }
+
+    line := formatLogLine(logger.now(), level, msg, keyvals...)
+    logger.stderr.Write(line)
+
+    if logger.file != nil {
+        if _, err := logger.file.Write(line); err != nil {
+            //Logging must never take the scope down, so just stop using the file
+            fmt.Fprintf(logger.stderr, "could not write log file %s: %s\n", logger.file.path, err)
+            logger.file.Close()
+            logger.file = nil
+        }
+    }
+}
+
+//formatLogLine formats a log entry as a logfmt line, keyvals are alternating keys and values
+func formatLogLine(t time.Time, level int, msg string, keyvals ...interface{}) []byte {
+    var b strings.Builder
+    fmt.Fprintf(&b, "time=%s level=%s msg=%s", t.UTC().Format(time.RFC3339), levelNames[level], logValue(msg))
+
+    for i := 0; i < len(keyvals); i += 2 {
+        var value interface{} = "(missing)"
+        if i + 1 < len(keyvals) {
+            value = keyvals[i + 1]
+        }
+
+        fmt.Fprintf(&b, " %s=%s", keyvals[i], logValue(fmt.Sprint(value)))
+    }
+
+    b.WriteString("\n")
+    return []byte(b.String())
+}
+
+func logValue(value string) string {
+    if value == "" || strings.ContainsAny(value, " \"=\n\t") {
+        return fmt.Sprintf("%q", value)
+    }
+
+    return value
+}
+
+type logWriter struct {
+    logger *Logger
+    level int
+}
+
+func (writer *logWriter) Write(p []byte) (int, error) {
+    writer.logger.log(writer.level, strings.TrimSpace(string(p)))
+    return len(p), nil
+}
+
+//rotatingFile appends to a file, renaming it to path.1, path.2, ... once it reaches maxSize
+type rotatingFile struct {
+    path string
+    maxSize int64
+    backups int
+    file *os.File
+    size int64
+}
+
+func (f *rotatingFile) open() error {
+    file, err := os.OpenFile(f.path, os.O_WRONLY | os.O_APPEND | os.O_CREATE, 0644)
+    if err != nil {
+        return err
+    }
+
+    info, err := file.Stat()
+    if err != nil {
+        file.Close()
+        return err
+    }
+
+    f.file = file
+    f.size = info.Size()
+    return nil
+}
+
+func (f *rotatingFile) rotate() error {
+    f.Close()
+
+    for i := f.backups; i > 0; i-- {
+        from := f.path
+        if i > 1 {
+            from = fmt.Sprintf("%s.%d", f.path, i - 1)
+        }
+
+        if err := os.Rename(from, fmt.Sprintf("%s.%d", f.path, i)); err != nil && !os.IsNotExist(err) {
+            return err
+        }
+    }
+
+    if f.backups == 0 {
+        if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+            return err
+        }
+    }
+
+    return f.open()
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+    if f.file == nil {
+        if err := f.open(); err != nil {
+            return 0, err
+        }
+    }
+
+    if f.size > 0 && f.size + int64(len(p)) > f.maxSize {
+        if err := f.rotate(); err != nil {
+            return 0, err
+        }
+    }
+
+    n, err := f.file.Write(p)
+    f.size += int64(n)
+    return n, err
+}
+
+func (f *rotatingFile) Close() error {
+    if f.file == nil {
+        return nil
+    }
+
+    err := f.file.Close()
+    f.file = nil
+    return err
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+    "bytes"
+    "errors"
+    "io/ioutil"
+    "log"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+func newTestLogger() (*Logger, *bytes.Buffer) {
+    var buf bytes.Buffer
+    logger := &Logger{
+        level: levelInfo,
+        stderr: &buf,
+        now: func() time.Time {
+            return time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC)
+        },
+    }
+
+    return logger, &buf
+}
+
+func TestFormatLogLine(t *testing.T) {
+    line := string(formatLogLine(time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC), levelWarn, "skipping file", "file", "/tmp/a b.desktop", "error", errors.New("denied"), "count"))
+    expected := `time=2015-06-01T12:00:00Z level=warn msg="skipping file" file="/tmp/a b.desktop" error=denied count=(missing)` + "\n"
+    if line != expected {
+        t.Errorf("expected %q, got %q", expected, line)
+    }
+}
+
+func TestLoggerLevel(t *testing.T) {
+    logger, buf := newTestLogger()
+
+    logger.Debug("hidden")
+    logger.Info("shown")
+    if strings.Contains(buf.String(), "hidden") || !strings.Contains(buf.String(), "shown") {
+        t.Errorf("unexpected output at info level: %q", buf.String())
+    }
+
+    buf.Reset()
+    logger.SetLevel("ERROR")
+    logger.Warn("hidden")
+    logger.Error("shown")
+    if strings.Contains(buf.String(), "hidden") || !strings.Contains(buf.String(), "level=error") {
+        t.Errorf("unexpected output at error level: %q", buf.String())
+    }
+
+    buf.Reset()
+    log.New(logger.Writer(levelError), "", 0).Printf("from %s", "log")
+    if !strings.Contains(buf.String(), `msg="from log"`) {
+        t.Errorf("unexpected output from writer: %q", buf.String())
+    }
+}
+
+func TestLoggerRotation(t *testing.T) {
+    dir, err := ioutil.TempDir("", "falcon-log")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+
+    path := filepath.Join(dir, "falcon.log")
+    logger, _ := newTestLogger()
+    logger.SetFile(path)
+    logger.file.maxSize = 200
+
+    for i := 0; i < 20; i++ {
+        logger.Info("filling the log file")
+    }
+
+    for _, name := range []string{path, path + ".1", path + ".2"} {
+        info, err := os.Stat(name)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if info.Size() > 200 {
+            t.Errorf("%s is %d bytes, larger than the maximum", name, info.Size())
+        }
+    }
+
+    if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+        t.Errorf("expected only %d backups, got %v", logBackups, err)
+    }
+}
+
+func TestLoggerFileFailure(t *testing.T) {
+    logger, buf := newTestLogger()
+    logger.SetFile(filepath.Join("does", "not", "exist", "falcon.log"))
+
+    logger.Info("first")
+    logger.Info("second")
+    if logger.file != nil {
+        t.Error("expected the log file to be dropped after failing")
+    }
+
+    if !strings.Contains(buf.String(), "could not write log file") || !strings.Contains(buf.String(), "second") {
+        t.Errorf("unexpected output: %q", buf.String())
+    }
+}
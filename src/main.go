@@ -0,0 +1,21 @@
+// +build !cli
+
+package main
+
+import (
+    "launchpad.net/go-unityscopes/v2"
+    "log"
+    "os"
+)
+
+func main() {
+    falcon := newFalcon()
+    falcon.log.Info("starting up")
+
+    callLog := log.New(falcon.log.Writer(levelInfo), "", 0)
+    scope := scopes.Chain(falcon, scopes.LoggingMiddleware(callLog), falcon.cache.Middleware())
+    if err := scopes.Run(scope); err != nil {
+        falcon.log.Error("scope stopped", "error", err)
+        os.Exit(1)
+    }
+}
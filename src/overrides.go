@@ -0,0 +1,326 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "launchpad.net/go-unityscopes/v2"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+    "unicode"
+)
+
+//Where the icons users can pick for an app are looked for, most preferred first
+var themeIconDirs = []string{
+    "/usr/share/icons/suru/apps/128/",
+    "/usr/share/icons/suru/apps/scalable/",
+    "/usr/share/icons/hicolor/128x128/apps/",
+    "/usr/share/icons/hicolor/scalable/apps/",
+}
+
+var themeIconExtensions = []string{".png", ".svg"}
+
+//Icons are picked in the preview's actions widget by this prefix and the name of the icon
+const overrideIconPrefix = "override-icon:"
+
+//The next page of icons is shown by this prefix and the number of the page
+const iconPagePrefix = "icon-page:"
+
+//Every icon is a button in the actions widget, so only this many are shown at once
+const iconsPerPage = 12
+
+//Parts of app ids and names that don't say anything about which icons suit the app
+var iconStopWords = map[string]bool{
+    "app": true,
+    "com": true,
+    "desktop": true,
+    "net": true,
+    "org": true,
+    "ubuntu": true,
+}
+
+const (
+    renameStep = "rename"
+    chooseIconStep = "choose-icon"
+)
+
+//Override replaces the title or icon given to an app by its desktop file, empty fields are left as they are
+type Override struct {
+    Title string `json:"title,omitempty"`
+    Icon string `json:"icon,omitempty"`
+}
+
+//applyOverride returns the app as the user has customized it
+func (state *State) applyOverride(app Application) Application {
+    override, ok := state.Overrides[app.Uri]
+    if !ok {
+        return app
+    }
+
+    if override.Title != "" {
+        app.Title = override.Title
+        app.Sort = strings.ToLower(app.Title)
+    }
+
+    if override.Icon != "" {
+        app.Icon = override.Icon
+    }
+
+    return app
+}
+
+func (state *State) HasOverride(uri string) bool {
+    _, ok := state.Overrides[uri]
+    return ok
+}
+
+//loadOverrides reads the overrides from file, which is where they will be saved from now on
+func (falcon *Falcon) loadOverrides(file string) {
+    overrides := map[string]Override{}
+
+    content, err := ioutil.ReadFile(file)
+    if err != nil {
+        //There is no overrides file until the first app is customized
+        if !os.IsNotExist(err) {
+            falcon.log.Warn("could not load overrides", "file", file, "error", err)
+        }
+    } else if err := json.Unmarshal(content, &overrides); err != nil {
+        falcon.log.Warn("could not parse overrides", "file", file, "error", err)
+        overrides = map[string]Override{}
+    }
+
+    falcon.store.Update(func(state *State) error {
+        state.OverridesFile = file
+        state.Overrides = overrides
+        return nil
+    })
+
+    falcon.cache.Purge()
+}
+
+//updateOverride changes the override of an app, the change is only published once it has been saved
+func (falcon *Falcon) updateOverride(uri string, update func(override Override) Override) error {
+    err := falcon.store.Update(func(state *State) error {
+        if state.OverridesFile == "" {
+            return errors.New("no overrides file to save to")
+        }
+
+        //Copy so readers of the current snapshot don't see the change
+        overrides := map[string]Override{}
+        for key, override := range state.Overrides {
+            overrides[key] = override
+        }
+
+        override := update(overrides[uri])
+        if override == (Override{}) {
+            delete(overrides, uri)
+        } else {
+            overrides[uri] = override
+        }
+
+        data, err := json.MarshalIndent(overrides, "", "    ")
+        if err == nil {
+            err = ioutil.WriteFile(state.OverridesFile, data, 0644)
+        }
+
+        if err != nil {
+            falcon.log.Error("could not save overrides", "file", state.OverridesFile, "error", err)
+            return err
+        }
+
+        state.Overrides = overrides
+        return nil
+    })
+
+    //The cached results show the old title and icon
+    falcon.cache.Purge()
+
+    return err
+}
+
+//renameApp sets the title of an app, an empty title restores the one from its desktop file
+func (falcon *Falcon) renameApp(app Application, title string) error {
+    if app.Uri == "" {
+        return errors.New("can't rename an app without a uri")
+    }
+
+    return falcon.updateOverride(app.Uri, func(override Override) Override {
+        override.Title = strings.TrimSpace(title)
+        return override
+    })
+}
+
+//setAppIcon sets the icon of an app to one from the icon theme
+func (falcon *Falcon) setAppIcon(app Application, name string) error {
+    icon := falcon.themeIcon(name)
+    if icon == "" {
+        return fmt.Errorf("no icon named %s", name)
+    }
+
+    return falcon.updateOverride(app.Uri, func(override Override) Override {
+        override.Icon = icon
+        return override
+    })
+}
+
+//resetApp clears the title and icon the user has given an app
+func (falcon *Falcon) resetApp(app Application) error {
+    return falcon.updateOverride(app.Uri, func(override Override) Override {
+        return Override{}
+    })
+}
+
+//themeIcons returns the names of the icons users can pick, sorted by name
+func (falcon *Falcon) themeIcons() []string {
+    found := map[string]bool{}
+    for _, dir := range falcon.iconDirs {
+        files, err := ioutil.ReadDir(dir)
+        if err != nil {
+            continue
+        }
+
+        for _, f := range files {
+            ext := filepath.Ext(f.Name())
+            for _, allowed := range themeIconExtensions {
+                if ext == allowed {
+                    found[strings.TrimSuffix(f.Name(), ext)] = true
+                }
+            }
+        }
+    }
+
+    var names []string
+    for name := range found {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    return names
+}
+
+//iconWords splits names into the lower cased words icons are matched by
+func iconWords(names ...string) []string {
+    var words []string
+    for _, name := range names {
+        for _, word := range strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+            return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+        }) {
+            if len(word) >= 3 && !iconStopWords[word] {
+                words = append(words, word)
+            }
+        }
+    }
+
+    return words
+}
+
+//appIcons returns the icons users can pick for an app, the ones named like the app or the command it runs first
+func (falcon *Falcon) appIcons(app Application) []string {
+    names := []string{app.Title, app.Id, strings.TrimSuffix(filepath.Base(app.Uri), ".desktop")}
+    if entry, ok := parseDesktopFile(app.Desktop)["Desktop Entry"]; ok {
+        if args, err := parseExec(entry["exec"]); err == nil && len(args) > 0 {
+            names = append(names, filepath.Base(args[0]))
+        }
+
+        names = append(names, filepath.Base(entry["icon"]))
+    }
+    words := iconWords(names...)
+
+    var related []string
+    var others []string
+    for _, icon := range falcon.themeIcons() {
+        matched := false
+        for _, word := range words {
+            if strings.Contains(strings.ToLower(icon), word) {
+                matched = true
+                break
+            }
+        }
+
+        if matched {
+            related = append(related, icon)
+        } else {
+            others = append(others, icon)
+        }
+    }
+
+    return append(related, others...)
+}
+
+//themeIcon resolves the name of an icon to its file, returning an empty string if the theme doesn't have it
+func (falcon *Falcon) themeIcon(name string) string {
+    if name == "" || strings.ContainsAny(name, "/") {
+        return ""
+    }
+
+    for _, dir := range falcon.iconDirs {
+        for _, ext := range themeIconExtensions {
+            file := filepath.Join(dir, name + ext)
+            if _, err := os.Stat(file); err == nil {
+                return "file://" + file
+            }
+        }
+    }
+
+    return ""
+}
+
+//previewRename asks the user for a new title for an app
+func (falcon *Falcon) previewRename(app Application, locale string, reply scopes.PreviewReplier) error {
+    headerWidget := scopes.NewPreviewWidget("header", "header")
+    headerWidget.AddAttributeValue("title", app.Title)
+
+    iconWidget := scopes.NewPreviewWidget("art", "image")
+    iconWidget.AddAttributeValue("source", app.Icon)
+
+    //The shell passes the text to PerformAction as the scope data of the rename widget
+    inputWidget := scopes.NewPreviewWidget(renameStep, "comment-input")
+    inputWidget.AddAttributeValue("submit-label", falcon.tr(locale, "Rename"))
+
+    return reply.PushWidgets(headerWidget, iconWidget, inputWidget)
+}
+
+//previewChooseIcon lists a page of the theme's icons for the user to pick one for an app, starting with the ones
+//related to the app
+func (falcon *Falcon) previewChooseIcon(app Application, page int, locale string, reply scopes.PreviewReplier) error {
+    headerWidget := scopes.NewPreviewWidget("header", "header")
+    headerWidget.AddAttributeValue("title", app.Title)
+
+    icons := falcon.appIcons(app)
+    //The theme may have changed since the page was asked for, so start again past its end
+    start := page * iconsPerPage
+    if page < 0 || start >= len(icons) {
+        page = 0
+        start = 0
+    }
+
+    end := start + iconsPerPage
+    if end > len(icons) {
+        end = len(icons)
+    }
+
+    var buttons []ActionInfo
+    for _, name := range icons[start:end] {
+        buttons = append(buttons, ActionInfo{Id: overrideIconPrefix + name, Label: name, Icon: falcon.themeIcon(name)})
+    }
+
+    if end < len(icons) {
+        buttons = append(buttons, ActionInfo{Id: iconPagePrefix + strconv.Itoa(page + 1), Label: falcon.tr(locale, "More Icons")})
+    }
+
+    if len(buttons) == 0 {
+        messageWidget := scopes.NewPreviewWidget("message", "text")
+        messageWidget.AddAttributeValue("text", falcon.tr(locale, "No icons found"))
+
+        return reply.PushWidgets(headerWidget, messageWidget)
+    }
+
+    actionsWidget := scopes.NewPreviewWidget("actions", "actions")
+    actionsWidget.AddAttributeValue("actions", buttons)
+
+    return reply.PushWidgets(headerWidget, actionsWidget)
+}
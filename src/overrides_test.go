@@ -0,0 +1,235 @@
+package main
+
+import (
+    "fmt"
+    "io/ioutil"
+    "launchpad.net/go-unityscopes/v2"
+    "launchpad.net/go-unityscopes/v2/scopestest"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+//searchTitles returns the titles of the apps and scopes found by a search in the given locale
+func searchTitles(t *testing.T, falcon *Falcon, query string, locale string) []string {
+    reply := scopestest.NewSearchReply()
+    q := scopes.NewCannedQuery("falcon.bhdouglass_falcon", query, "")
+    if err := falcon.Search(q, scopes.NewSearchMetadata(0, locale, "phone"), reply, nil); err != nil {
+        t.Fatal(err)
+    }
+
+    var titles []string
+    for _, result := range reply.Results {
+        var title string
+        if err := result.Get("title", &title); err != nil {
+            t.Fatal(err)
+        }
+
+        if result.Category().Id() != "store" {
+            titles = append(titles, title)
+        }
+    }
+
+    return titles
+}
+
+func TestOverrides(t *testing.T) {
+    dir, err := ioutil.TempDir("", "falcon-overrides")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+
+    falcon := newTestFalcon(layoutFirstLetter)
+    falcon.iconDirs = []string{filepath.Join("testdata", "libertine", "xenial", "rootfs", "usr", "share", "icons", "hicolor", "48x48", "apps")}
+    falcon.loadOverrides(filepath.Join(dir, "overrides.json"))
+
+    calculator := Application{Uri: "application:///calculator.desktop", Title: "Calculator"}
+    if err := falcon.renameApp(calculator, " Sums "); err != nil {
+        t.Fatal(err)
+    }
+
+    if err := falcon.setAppIcon(calculator, "gimp"); err != nil {
+        t.Fatal(err)
+    }
+
+    if err := falcon.setAppIcon(calculator, "missing"); err == nil {
+        t.Error("expected an error picking an icon the theme doesn't have")
+    }
+
+    //Overrides are applied before searching, so the new title is found and the old one isn't
+    for query, expected := range map[string][]string{"sum": {"Sums"}, "calc": nil} {
+        if titles := searchTitles(t, falcon, query, "en_US"); !reflect.DeepEqual(titles, expected) {
+            t.Errorf("expected %q searching for %q, got %q", expected, query, titles)
+        }
+    }
+
+    app, _ := falcon.lookupApp(calculator.Uri)
+    icon := "file://" + filepath.Join(falcon.iconDirs[0], "gimp.png")
+    if app.Title != "Sums" || app.Sort != "sums" || app.Icon != icon {
+        t.Errorf("expected the overrides to be applied, got %+v", app)
+    }
+
+    //A new Falcon reads the saved overrides
+    reloaded := newTestFalcon(layoutFirstLetter)
+    reloaded.loadOverrides(filepath.Join(dir, "overrides.json"))
+    if override := reloaded.store.Snapshot().Overrides[calculator.Uri]; override != (Override{Title: "Sums", Icon: icon}) {
+        t.Errorf("expected the overrides to be saved, got %+v", override)
+    }
+
+    if err := falcon.resetApp(calculator); err != nil {
+        t.Fatal(err)
+    }
+
+    if falcon.store.Snapshot().HasOverride(calculator.Uri) {
+        t.Error("expected reset to clear the overrides")
+    }
+}
+
+func TestOverridePreviewWithoutSearch(t *testing.T) {
+    dir, err := ioutil.TempDir("", "falcon-overrides")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+
+    falcon := newTestFalcon(layoutFirstLetter)
+    falcon.loadOverrides(filepath.Join(dir, "overrides.json"))
+    searchTitles(t, falcon, "calc", "C")
+
+    calculator, ok := falcon.lookupApp("application:///calculator.desktop")
+    if !ok {
+        t.Fatal("expected the search to find the calculator")
+    }
+
+    //The shell shows the preview again straight after the action, before any search
+    for _, step := range []struct {
+        action string
+        title string
+    }{{"rename", "Sums"}, {"reset", "Calculator"}} {
+        if step.action == "rename" {
+            err = falcon.renameApp(calculator, step.title)
+        } else {
+            _, err = falcon.performAppAction(calculator, step.action)
+        }
+
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        reply := previewResult(t, falcon, calculator)
+        if title := reply.Widget("header")["title"]; title != step.title {
+            t.Errorf("expected the preview after %s to show %q, got %v", step.action, step.title, title)
+        }
+    }
+}
+
+func TestThemeIcons(t *testing.T) {
+    falcon := newFalcon()
+    falcon.iconDirs = []string{
+        filepath.Join("testdata", "libertine", "xenial", "rootfs", "usr", "share", "icons", "hicolor", "48x48", "apps"),
+        filepath.Join("testdata", "missing"),
+    }
+
+    if icons := falcon.themeIcons(); !reflect.DeepEqual(icons, []string{"gimp"}) {
+        t.Errorf("expected the icons in the theme, got %q", icons)
+    }
+
+    if icon := falcon.themeIcon("../48x48/apps/gimp"); icon != "" {
+        t.Errorf("expected names with a path to be refused, got %q", icon)
+    }
+}
+
+func TestChooseIconPages(t *testing.T) {
+    dir, err := ioutil.TempDir("", "falcon-icons")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+
+    icons := []string{"accessories-calculator.png", "gcalctool.svg", "ubuntu-logo.png"}
+    for i := 0; i < iconsPerPage; i++ {
+        icons = append(icons, fmt.Sprintf("icon-%02d.png", i))
+    }
+
+    for _, icon := range icons {
+        if err := ioutil.WriteFile(filepath.Join(dir, icon), nil, 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    falcon := newTestFalcon(layoutFirstLetter)
+    falcon.iconDirs = []string{dir}
+    app := Application{
+        Id: "com.ubuntu.calculator_calculator_2.0",
+        Uri: "application:///com.ubuntu.calculator_calculator_2.0.desktop",
+        Title: "Calculator",
+        Desktop: "[Desktop Entry]\nName=Calculator\nExec=gcalctool %U\n",
+    }
+
+    //The icons named like the app or its command come first, ubuntu alone doesn't relate an icon to the app
+    if related := falcon.appIcons(app)[:3]; !reflect.DeepEqual(related, []string{"accessories-calculator", "gcalctool", "icon-00"}) {
+        t.Errorf("expected the related icons first, got %q", related)
+    }
+
+    data := PreviewData{Step: chooseIconStep}
+    var shown []string
+    for page := 0; page < 3 && data.Step == chooseIconStep; page++ {
+        reply := scopestest.NewPreviewReply()
+        if err := falcon.previewStep(app, data, "en_US", reply); err != nil {
+            t.Fatal(err)
+        }
+
+        data = PreviewData{}
+        actions := reply.Widget("actions")["actions"].([]ActionInfo)
+        if len(actions) > iconsPerPage + 1 {
+            t.Errorf("expected at most %d icons on a page, got %d", iconsPerPage, len(actions))
+        }
+
+        for _, action := range actions {
+            if strings.HasPrefix(action.Id, overrideIconPrefix) {
+                shown = append(shown, action.Label)
+                continue
+            }
+
+            resp, err := falcon.performAppAction(app, action.Id)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            data = resp.ScopeData.(PreviewData)
+        }
+    }
+
+    if len(shown) != len(icons) || data.Step != "" {
+        t.Errorf("expected every icon to be shown across the pages, got %q", shown)
+    }
+
+    if _, err := falcon.performAppAction(app, iconPagePrefix + "next"); err == nil {
+        t.Error("expected an error for an invalid page")
+    }
+}
+
+func TestOverridePreviewSteps(t *testing.T) {
+    falcon := newTestFalcon(layoutFirstLetter)
+    app := Application{Uri: "application:///calculator.desktop", Title: "Calculator"}
+
+    for action, widget := range map[string]string{"rename": "comment-input", "change-icon": "text"} {
+        resp, err := falcon.performAppAction(app, action)
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        reply := scopestest.NewPreviewReply()
+        if err := falcon.previewStep(app, resp.ScopeData.(PreviewData), "en_US", reply); err != nil {
+            t.Fatal(err)
+        }
+
+        //No icon dirs exist in the tests, so choosing an icon only shows a message
+        if last := reply.Widgets[len(reply.Widgets) - 1]; last.WidgetType() != widget {
+            t.Errorf("expected %s to end with a %s widget, got %v", action, widget, reply.Widgets)
+        }
+    }
+}
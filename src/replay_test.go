@@ -0,0 +1,127 @@
+package main
+
+import (
+    "encoding/json"
+    "flag"
+    "io/ioutil"
+    "launchpad.net/go-unityscopes/v2"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden recordings in testdata with the current output")
+
+//newTestFalcon returns a Falcon reading apps and scopes from testdata
+func newTestFalcon(layout int64) *Falcon {
+    falcon := newFalcon()
+    falcon.appDirs = []string{filepath.Join("testdata", "applications")}
+    falcon.remoteScopesFile = filepath.Join("testdata", "remote-scopes.json")
+    falcon.libertineDir = ""
+    falcon.clickRoots = nil
+    falcon.preinstalledRoots = nil
+    falcon.iconDirs = nil
+    falcon.store.Update(func(state *State) error {
+        state.Settings.Layout = layout
+        return nil
+    })
+
+    return falcon
+}
+
+func replayFile(t *testing.T, falcon *Falcon, file string) ([]scopes.RecordEntry, []scopes.RecordEntry) {
+    f, err := os.Open(file)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer f.Close()
+
+    recording, err := scopes.ReadRecording(f)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    replayed, err := scopes.Replay(falcon, recording)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    return recording, replayed
+}
+
+//replayGolden replays a recording from testdata against a Falcon reading apps and scopes from testdata
+func replayGolden(t *testing.T, layout int64, name string) {
+    file := filepath.Join("testdata", name)
+    recording, replayed := replayFile(t, newTestFalcon(layout), file)
+
+    if *update {
+        var data []byte
+        for _, entry := range replayed {
+            line, err := json.Marshal(entry)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            data = append(append(data, line...), '\n')
+        }
+
+        if err := ioutil.WriteFile(file, data, 0644); err != nil {
+            t.Fatal(err)
+        }
+
+        return
+    }
+
+    for _, diff := range scopes.DiffRecordings(recording, replayed) {
+        t.Error(diff)
+    }
+}
+
+func TestGoldenLayoutAppsScopes(t *testing.T) {
+    replayGolden(t, layoutAppsScopes, "layout-apps-scopes.jsonl")
+}
+
+func TestGoldenLayoutFirstLetter(t *testing.T) {
+    replayGolden(t, layoutFirstLetter, "layout-first-letter.jsonl")
+}
+
+func TestUnreadableDesktopFileIsSkipped(t *testing.T) {
+    dir, err := ioutil.TempDir("", "falcon-apps")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+
+    files, err := ioutil.ReadDir(filepath.Join("testdata", "applications"))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for _, f := range files {
+        content, err := ioutil.ReadFile(filepath.Join("testdata", "applications", f.Name()))
+        if err != nil {
+            t.Fatal(err)
+        }
+
+        if err := ioutil.WriteFile(filepath.Join(dir, f.Name()), content, 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    //Reading a directory fails, which used to stop Falcon
+    if err := os.Mkdir(filepath.Join(dir, "broken.desktop"), 0755); err != nil {
+        t.Fatal(err)
+    }
+
+    falcon := newTestFalcon(layoutAppsScopes)
+    falcon.appDirs = []string{dir}
+
+    recording, replayed := replayFile(t, falcon, filepath.Join("testdata", "layout-apps-scopes.jsonl"))
+    for _, diff := range scopes.DiffRecordings(recording, replayed) {
+        t.Error(diff)
+    }
+}
+
+func TestGoldenLayoutCategories(t *testing.T) {
+    replayGolden(t, layoutCategories, "layout-categories.jsonl")
+}
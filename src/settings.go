@@ -0,0 +1,54 @@
+package main
+
+import (
+    "launchpad.net/go-unityscopes/v2"
+)
+
+const (
+    layoutAppsScopes  = 0
+    layoutFirstLetter = 1
+    layoutCategories  = 2
+)
+
+//SettingsDefinition is used to write the settings ini file, so it includes every language Falcon has been translated to
+func (falcon *Falcon) SettingsDefinition() *scopes.SettingsDefinition {
+    definition := falcon.settingsDefinition("")
+    for _, lang := range falcon.localeCatalogs() {
+        for index, localized := range falcon.settingsDefinition(lang).Settings {
+            definition.Settings[index].Localize(lang, localized.DisplayName, localized.DisplayValues...)
+        }
+    }
+
+    return definition
+}
+
+func (falcon *Falcon) settingsDefinition(locale string) *scopes.SettingsDefinition {
+    return scopes.NewSettingsDefinition(
+        scopes.NewListSetting("layout", falcon.tr(locale, "Layout"), []string{
+            falcon.tr(locale, "Group Apps & Scopes"),
+            falcon.tr(locale, "Group by First Letter"),
+            falcon.tr(locale, "Group by Category"),
+        }, layoutAppsScopes),
+        scopes.NewBooleanSetting("webapps", falcon.tr(locale, "List Web Apps Separately"), false),
+    )
+}
+
+//SettingsChanged is called when the runtime's poll before a request finds new settings.
+//The decoded settings are kept in the store, so requests never read them from the runtime themselves
+func (falcon *Falcon) SettingsChanged() {
+    falcon.log.Info("settings changed")
+    falcon.loadSettings()
+    falcon.cache.Purge()
+}
+
+func (falcon *Falcon) loadSettings() {
+    var settings Settings
+    if err := falcon.base.Settings(&settings); err != nil {
+        falcon.log.Warn("could not load settings, using the defaults", "error", err)
+    }
+
+    falcon.store.Update(func(state *State) error {
+        state.Settings = settings
+        return nil
+    })
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+    "sync"
+    "sync/atomic"
+)
+
+//State is a snapshot of Falcon's mutable state. Snapshots are shared between goroutines, so they must never be modified
+type State struct {
+    FavFile string
+    Favorites []string
+    Settings Settings
+    //Every installed app and scope as found by the last search, keyed by uri. The overrides are applied when they are
+    //looked up, so a change to them shows straight away
+    Apps map[string]Application
+    OverridesFile string
+    //The titles and icons users have given apps, keyed by uri
+    Overrides map[string]Override
+}
+
+func (state *State) IsFavorite(appId string) bool {
+    for _, id := range state.Favorites {
+        if id == appId {
+            return true
+        }
+    }
+
+    return false
+}
+
+//Store holds Falcon's state. Search, Preview, Activate and PerformAction run concurrently, so readers take a snapshot
+//and writers publish a modified copy instead of changing the state in place
+type Store struct {
+    //Only one update runs at a time, so updates are never lost
+    lock sync.Mutex
+    state atomic.Value
+}
+
+func newStore() *Store {
+    store := &Store{}
+    store.state.Store(&State{})
+
+    return store
+}
+
+//Snapshot returns the current state, which stays the same however the store is updated afterwards
+func (store *Store) Snapshot() *State {
+    return store.state.Load().(*State)
+}
+
+//Update calls update with a copy of the current state and publishes it unless update returns an error.
+//The copy shares its slices and maps with the current state, so update must replace them rather than modify them.
+func (store *Store) Update(update func(state *State) error) error {
+    store.lock.Lock()
+    defer store.lock.Unlock()
+
+    state := *store.Snapshot()
+    if err := update(&state); err != nil {
+        return err
+    }
+
+    store.state.Store(&state)
+    return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+    "fmt"
+    "testing"
+)
+
+func TestStoreSnapshot(t *testing.T) {
+    store := newStore()
+    store.Update(func(state *State) error {
+        state.Favorites = []string{"calculator"}
+        return nil
+    })
+
+    snapshot := store.Snapshot()
+    store.Update(func(state *State) error {
+        state.Favorites = nil
+        state.Settings.Layout = layoutFirstLetter
+        return nil
+    })
+
+    if !snapshot.IsFavorite("calculator") || snapshot.Settings.Layout != layoutAppsScopes {
+        t.Errorf("snapshot changed after an update: %+v", snapshot)
+    }
+
+    if err := store.Update(func(state *State) error {
+        state.Favorites = []string{"terminal"}
+        return fmt.Errorf("failed")
+    }); err == nil {
+        t.Error("expected the update's error")
+    }
+
+    if store.Snapshot().IsFavorite("terminal") {
+        t.Error("a failed update was published")
+    }
+}
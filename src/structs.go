@@ -1,7 +1,8 @@
 package main
 
 type Settings struct {
-    Layout int64 `json:"layout"`
+    Layout  int64 `json:"layout"`
+    Webapps bool  `json:"webapps"`
 }
 
 type ActionInfo struct {
@@ -12,14 +13,40 @@ type ActionInfo struct {
 }
 
 type Application struct {
-    Id      string
-    Title   string
-    Comment string
-    Icon    string
-    Uri     string
-    Desktop string
-    IsApp   bool
-    Sort    string
+    Id        string
+    Title     string
+    Comment   string
+    Icon      string
+    Uri       string
+    Desktop   string
+    IsApp     bool
+    Sort      string
+    //The freedesktop main category of the app, empty if it has none
+    Category  string
+    //The Libertine container a desktop app is installed in, empty for phone apps and scopes
+    Container string
+    //Whether the app runs in webapp-container or as a webapp of the browser
+    IsWebapp  bool
+    //The site opened by a webapp, empty for native apps and webapps whose site couldn't be found
+    WebappUrl string
+    //The directory the app's desktop file was found in, empty for desktop apps and results from an older search
+    DesktopDir string
+}
+
+//PreviewData is passed from an activation response to the preview it shows
+type PreviewData struct {
+    Step string `json:"step,omitempty"`
+    //The page of a step that is split into pages, starting from 0
+    Page int `json:"page,omitempty"`
+}
+
+type AppPayload struct {
+    Version int    `json:"v"`
+    Id      string `json:"id,omitempty"`
+    Uri     string `json:"uri"`
+    Title   string `json:"title"`
+    Icon    string `json:"icon,omitempty"`
+    IsApp   bool   `json:"app,omitempty"`
 }
 
 type RemoteScope struct {
@@ -0,0 +1,158 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "launchpad.net/go-unityscopes/v2"
+    "os"
+    "os/exec"
+    "os/user"
+    "path/filepath"
+    "strings"
+)
+
+//Apps in these directories come with the system image and can't be uninstalled
+var systemAppDirs = []string{
+    "/usr/share/applications/",
+}
+
+//Click packages under these directories come with the system image or the device's custom image. They can't be
+//removed with click, so the store is opened for them instead
+var preinstalledClickRoots = []string{
+    "/usr/share/click/preinstalled/",
+    "/custom/click/",
+}
+
+//The store scope, which is searched for preinstalled packages so the user can uninstall them there
+const clickStoreScope = "com.canonical.scopes.clickstore"
+
+//The preview asks for confirmation when it is shown again with this step in its scope data
+const uninstallConfirmStep = "confirm-uninstall"
+
+//PackageManager removes click packages, the tests replace it with a fake
+type PackageManager interface {
+    Remove(pkg string, version string) error
+}
+
+//clickPackageManager is Falcon's default package manager, it unregisters packages for the user running the scope with
+//the click command, which also removes their desktop files
+type clickPackageManager struct {
+    //The click command, the tests replace it with a script
+    command string
+}
+
+func (packages clickPackageManager) Remove(pkg string, version string) error {
+    command := packages.command
+    if command == "" {
+        command = "click"
+    }
+
+    name := os.Getenv("USER")
+    if current, err := user.Current(); err == nil {
+        name = current.Username
+    }
+
+    output, err := exec.Command(command, "unregister", "--user=" + name, pkg, version).CombinedOutput()
+    if err != nil {
+        return fmt.Errorf("click unregister %s %s failed: %s: %s", pkg, version, err, strings.TrimSpace(string(output)))
+    }
+
+    return nil
+}
+
+//canUninstall reports whether an app is from a click package, either installed by the user or preinstalled
+func (falcon *Falcon) canUninstall(app Application) bool {
+    if !app.IsApp || app.Container != "" || app.DesktopDir == "" {
+        return false
+    }
+
+    if _, _, _, ok := splitAppId(app.Id); !ok {
+        return false
+    }
+
+    for _, dir := range systemAppDirs {
+        if filepath.Clean(app.DesktopDir) == filepath.Clean(dir) {
+            return false
+        }
+    }
+
+    return true
+}
+
+//isPreinstalled reports whether the version of an app's click package came with the image. Their desktop files are in
+//the user's applications directory too
+func (falcon *Falcon) isPreinstalled(app Application) bool {
+    pkg, _, version, ok := splitAppId(app.Id)
+    if !ok {
+        return false
+    }
+
+    for _, root := range falcon.preinstalledRoots {
+        if _, err := os.Stat(filepath.Join(root, pkg, version)); err == nil {
+            return true
+        }
+    }
+
+    return false
+}
+
+//previewUninstall asks the user to confirm uninstalling an app
+func (falcon *Falcon) previewUninstall(app Application, locale string, reply scopes.PreviewReplier) error {
+    headerWidget := scopes.NewPreviewWidget("header", "header")
+    headerWidget.AddAttributeValue("title", app.Title)
+
+    iconWidget := scopes.NewPreviewWidget("art", "image")
+    iconWidget.AddAttributeValue("source", app.Icon)
+
+    messageWidget := scopes.NewPreviewWidget("message", "text")
+    messageWidget.AddAttributeValue("text", fmt.Sprintf(falcon.tr(locale, "Are you sure you want to uninstall %s?"), app.Title))
+
+    actionsWidget := scopes.NewPreviewWidget("actions", "actions")
+    actionsWidget.AddAttributeValue("actions", []ActionInfo{
+        {Id: "uninstall-confirm", Label: falcon.tr(locale, "Uninstall")},
+        {Id: "uninstall-cancel", Label: falcon.tr(locale, "Cancel")},
+    })
+
+    return reply.PushWidgets(headerWidget, iconWidget, messageWidget, actionsWidget)
+}
+
+//uninstall removes the click package of an app and forgets the app, so it disappears from the next search.
+//Preinstalled packages are left to the store, which is searched for the package, and the app is kept
+func (falcon *Falcon) uninstall(app Application) (*scopes.ActivationResponse, error) {
+    if !falcon.canUninstall(app) {
+        return nil, fmt.Errorf("%s can't be uninstalled", app.Uri)
+    }
+
+    pkg, _, version, _ := splitAppId(app.Id)
+    if falcon.isPreinstalled(app) {
+        falcon.log.Info("opening the store to uninstall a preinstalled app", "app", app.Id)
+        return scopes.NewActivationResponseForQuery(scopes.NewCannedQuery(clickStoreScope, pkg, "")), nil
+    }
+
+    if falcon.packages == nil {
+        return nil, errors.New("no package manager to uninstall with")
+    }
+
+    falcon.log.Info("uninstalling app", "app", app.Id)
+    if err := falcon.packages.Remove(pkg, version); err != nil {
+        falcon.log.Error("could not uninstall app", "app", app.Id, "error", err)
+        return nil, err
+    }
+
+    falcon.store.Update(func(state *State) error {
+        apps := map[string]Application{}
+        for uri, other := range state.Apps {
+            if uri != app.Uri {
+                apps[uri] = other
+            }
+        }
+
+        state.Apps = apps
+        return nil
+    })
+
+    //The cached results still list the app
+    falcon.cache.Purge()
+
+    return scopes.NewActivationResponse(scopes.ActivationShowDash), nil
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+    "errors"
+    "io/ioutil"
+    "launchpad.net/go-unityscopes/v2"
+    "launchpad.net/go-unityscopes/v2/scopestest"
+    "os"
+    osuser "os/user"
+    "path/filepath"
+    "reflect"
+    "testing"
+)
+
+type fakePackageManager struct {
+    removed [][]string
+    err error
+}
+
+func (packages *fakePackageManager) Remove(pkg string, version string) error {
+    if packages.err != nil {
+        return packages.err
+    }
+
+    packages.removed = append(packages.removed, []string{pkg, version})
+    return nil
+}
+
+var notesApp = Application{
+    Id: "com.example.notes_notes_1.2.3",
+    Title: "Notes",
+    Uri: "application:///com.example.notes_notes_1.2.3.desktop",
+    IsApp: true,
+    DesktopDir: "/home/phablet/.local/share/applications/",
+}
+
+func TestCanUninstall(t *testing.T) {
+    falcon := newFalcon()
+    falcon.preinstalledRoots = []string{filepath.Join("testdata", "preinstalled"), filepath.Join("testdata", "missing")}
+    if !falcon.canUninstall(notesApp) {
+        t.Error("expected an installed click app to be uninstallable")
+    }
+
+    system := notesApp
+    system.DesktopDir = "/usr/share/applications"
+
+    container := notesApp
+    container.Container = "xenial"
+
+    legacy := notesApp
+    legacy.Id = "webbrowser-app"
+
+    scope := notesApp
+    scope.IsApp = false
+
+    for _, app := range []Application{system, container, legacy, scope, {Id: notesApp.Id, IsApp: true}} {
+        if falcon.canUninstall(app) {
+            t.Errorf("expected %+v not to be uninstallable", app)
+        }
+    }
+
+    //Preinstalled click apps have their desktop files with the ones the user installed, only the store removes them
+    preinstalled := notesApp
+    preinstalled.Id = "com.example.clock_clock_2.0"
+    if !falcon.canUninstall(preinstalled) || !falcon.isPreinstalled(preinstalled) || falcon.isPreinstalled(notesApp) {
+        t.Error("expected only the preinstalled app to be found in the preinstalled packages")
+    }
+}
+
+func TestUninstall(t *testing.T) {
+    falcon := newTestFalcon(layoutAppsScopes)
+    packages := &fakePackageManager{}
+    falcon.packages = packages
+    falcon.storeApps(map[string]Application{notesApp.Uri: notesApp})
+
+    resp, err := falcon.performAppAction(notesApp, "uninstall")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    //Nothing is removed until the user confirms
+    if resp.Status != scopes.ActivationShowPreview || len(packages.removed) != 0 {
+        t.Fatalf("expected the preview to ask for confirmation, got %+v", resp)
+    }
+
+    reply := scopestest.NewPreviewReply()
+    if err := falcon.previewStep(notesApp, resp.ScopeData.(PreviewData), "en_US", reply); err != nil {
+        t.Fatal(err)
+    }
+
+    actions := reply.Widgets[len(reply.Widgets) - 1]["actions"].([]ActionInfo)
+    if actions[0].Id != "uninstall-confirm" || actions[1].Id != "uninstall-cancel" {
+        t.Errorf("expected the confirmation actions, got %+v", actions)
+    }
+
+    resp, err = falcon.performAppAction(notesApp, "uninstall-confirm")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if resp.Status != scopes.ActivationShowDash {
+        t.Errorf("expected the dash to be shown, got %v", resp.Status)
+    }
+
+    if expected := [][]string{{"com.example.notes", "1.2.3"}}; !reflect.DeepEqual(packages.removed, expected) {
+        t.Errorf("expected %q to be removed, got %q", expected, packages.removed)
+    }
+
+    if _, ok := falcon.lookupApp(notesApp.Uri); ok {
+        t.Error("expected the uninstalled app to be forgotten")
+    }
+}
+
+func TestUninstallPreinstalled(t *testing.T) {
+    falcon := newTestFalcon(layoutAppsScopes)
+    packages := &fakePackageManager{}
+    falcon.packages = packages
+    falcon.preinstalledRoots = []string{filepath.Join("testdata", "preinstalled")}
+
+    clock := notesApp
+    clock.Id = "com.example.clock_clock_2.0"
+    clock.Uri = "application:///com.example.clock_clock_2.0.desktop"
+    falcon.storeApps(map[string]Application{clock.Uri: clock})
+
+    //Click can't remove packages that came with the image, so the store is searched for the package instead
+    resp, err := falcon.performAppAction(clock, "uninstall-confirm")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if resp.Status != scopes.ActivationPerformQuery || resp.Query.ScopeID() != clickStoreScope || resp.Query.QueryString() != "com.example.clock" {
+        t.Errorf("expected a search of the store for the package, got %+v", resp)
+    }
+
+    if _, ok := falcon.lookupApp(clock.Uri); !ok || len(packages.removed) != 0 {
+        t.Error("expected the app to be kept until the store uninstalls it")
+    }
+}
+
+func TestUninstallWithClick(t *testing.T) {
+    dir, err := ioutil.TempDir("", "falcon-uninstall")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+
+    apps := filepath.Join(dir, "applications")
+    if err := os.Mkdir(apps, 0755); err != nil {
+        t.Fatal(err)
+    }
+
+    desktop := filepath.Join(apps, "com.example.notes_notes_1.2.3.desktop")
+    content := "[Desktop Entry]\nName=Notes\nExec=notes\nX-Ubuntu-Touch=true\nX-Ubuntu-Application-ID=com.example.notes_notes_1.2.3\n"
+    if err := ioutil.WriteFile(desktop, []byte(content), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    //The fake click records its arguments and removes the desktop file, as click's hooks do
+    args := filepath.Join(dir, "args")
+    script := filepath.Join(dir, "click")
+    if err := ioutil.WriteFile(script, []byte("#!/bin/sh\necho \"$@\" > " + args + "\nrm " + desktop + "\n"), 0755); err != nil {
+        t.Fatal(err)
+    }
+
+    falcon := newTestFalcon(layoutAppsScopes)
+    falcon.appDirs = []string{apps}
+    falcon.packages = clickPackageManager{command: script}
+    scope := scopes.Chain(falcon, falcon.cache.Middleware())
+
+    search := func() map[string]bool {
+        reply := scopestest.NewSearchReply()
+        if err := scope.Search(scopes.NewCannedQuery("falcon.bhdouglass_falcon", "", ""), scopes.NewSearchMetadata(0, "C", "phone"), reply, nil); err != nil {
+            t.Fatal(err)
+        }
+
+        uris := map[string]bool{}
+        for _, result := range reply.Results {
+            var uri string
+            if err := result.Get("uri", &uri); err != nil {
+                t.Fatal(err)
+            }
+
+            uris[uri] = true
+        }
+
+        return uris
+    }
+
+    //The surfacing results are cached, so this search is answered from the cache unless uninstalling purged it
+    notes := "application:///com.example.notes_notes_1.2.3.desktop"
+    search()
+    if uris := search(); !uris[notes] {
+        t.Fatalf("expected the app to be found before uninstalling it, got %v", uris)
+    }
+
+    app, _ := falcon.lookupApp(notes)
+    resp, err := falcon.performAppAction(app, "uninstall-confirm")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if resp.Status != scopes.ActivationShowDash {
+        t.Errorf("expected the dash to be shown, got %v", resp.Status)
+    }
+
+    user := os.Getenv("USER")
+    if current, err := osuser.Current(); err == nil {
+        user = current.Username
+    }
+
+    called, _ := ioutil.ReadFile(args)
+    if expected := "unregister --user=" + user + " com.example.notes 1.2.3\n"; string(called) != expected {
+        t.Errorf("expected click to be run with %q, got %q", expected, called)
+    }
+
+    if uris := search(); uris[notes] {
+        t.Errorf("expected the uninstalled app to be gone from the next search, got %v", uris)
+    }
+}
+
+func TestUninstallFailure(t *testing.T) {
+    falcon := newTestFalcon(layoutAppsScopes)
+    falcon.packages = &fakePackageManager{err: errors.New("click failed")}
+    falcon.storeApps(map[string]Application{notesApp.Uri: notesApp})
+
+    if _, err := falcon.performAppAction(notesApp, "uninstall-confirm"); err == nil {
+        t.Error("expected the package manager's error")
+    }
+
+    if _, ok := falcon.lookupApp(notesApp.Uri); !ok {
+        t.Error("expected the app to be kept when it couldn't be uninstalled")
+    }
+
+    system := notesApp
+    system.DesktopDir = "/usr/share/applications/"
+    if _, err := falcon.performAppAction(system, "uninstall"); err == nil {
+        t.Error("expected an error uninstalling a system app")
+    }
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+    "net/url"
+    "path/filepath"
+    "strings"
+)
+
+//webapp reports whether a desktop entry is a webapp, and the site it opens if that can be found. Webapps run in
+//webapp-container, or in the browser with --webapp options or as a single instance on older images
+func webapp(desktopMap map[string]string) (bool, string) {
+    args, err := parseExec(desktopMap["exec"])
+    if err != nil {
+        return false, ""
+    }
+
+    //Click apps are started through aa-exec-click, so the container can be any of the arguments
+    isWebapp := false
+    browser := false
+    site := ""
+    var patterns []string
+    for _, arg := range args {
+        switch {
+        case filepath.Base(arg) == "webapp-container":
+            isWebapp = true
+        case filepath.Base(arg) == "webbrowser-app":
+            browser = true
+        case arg == "--webapp" || strings.HasPrefix(arg, "--webapp="):
+            isWebapp = true
+        case strings.HasPrefix(arg, "--webappUrlPatterns="):
+            isWebapp = true
+            patterns = append(patterns, strings.Split(strings.TrimPrefix(arg, "--webappUrlPatterns="), ",")...)
+        case strings.HasPrefix(arg, "--homepage="):
+            if site == "" {
+                site = siteUrl(strings.TrimPrefix(arg, "--homepage="))
+            }
+        case !strings.HasPrefix(arg, "-") && site == "":
+            site = siteUrl(arg)
+        }
+    }
+
+    //The browser's own entry is a single instance too, but it doesn't open a site
+    if browser && site != "" && strings.ToLower(desktopMap["x-ubuntu-single-instance"]) == "true" {
+        isWebapp = true
+    }
+
+    if !isWebapp {
+        return false, ""
+    }
+
+    if site == "" {
+        site = patternSite(patterns)
+    }
+
+    return true, site
+}
+
+//siteUrl returns the argument if it's a http or https url, or an empty string
+func siteUrl(arg string) string {
+    if u, err := url.Parse(arg); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+        return arg
+    }
+
+    return ""
+}
+
+//patternSite guesses a webapp's site from its url patterns when it isn't given, https?://*.example.com/* becomes
+//https://example.com/
+func patternSite(patterns []string) string {
+    for _, pattern := range patterns {
+        parts := strings.SplitN(strings.TrimSpace(pattern), "://", 2)
+        if len(parts) != 2 {
+            continue
+        }
+
+        //https? matches both, so prefer the secure site
+        scheme := strings.TrimSuffix(parts[0], "?")
+        host := strings.TrimPrefix(strings.SplitN(parts[1], "/", 2)[0], "*.")
+        if host == "" || strings.ContainsAny(host, "*?") {
+            continue
+        }
+
+        if site := siteUrl(scheme + "://" + host + "/"); site != "" {
+            return site
+        }
+    }
+
+    return ""
+}
+
+//webappDomain returns the domain of a webapp's site, without the www. most sites are served from
+func webappDomain(site string) string {
+    u, err := url.Parse(site)
+    if err != nil {
+        return ""
+    }
+
+    return strings.TrimPrefix(u.Hostname(), "www.")
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+    "encoding/json"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+const twitterDesktop = `[Desktop Entry]
+Name=Twitter
+Comment=Twitter webapp
+Exec=aa-exec-click -p com.ubuntu.developer.webapps.webapp-twitter_webapp-twitter_1.0 -- webapp-container --enable-back-forward --store-session-cookies --webappUrlPatterns=https?://*.twitter.com/* https://www.twitter.com/ %u
+Icon=/opt/click.ubuntu.com/com.ubuntu.developer.webapps.webapp-twitter/1.0/twitter.png
+Type=Application
+X-Ubuntu-Touch=true
+X-Ubuntu-Single-Instance=true
+X-Ubuntu-Application-ID=com.ubuntu.developer.webapps.webapp-twitter_webapp-twitter_1.0
+`
+
+func TestWebapp(t *testing.T) {
+    cases := []struct {
+        exec string
+        singleInstance string
+        isWebapp bool
+        site string
+    }{
+        {"webapp-container --webappUrlPatterns=https?://m.facebook.com/* https://m.facebook.com/ %u", "", true, "https://m.facebook.com/"},
+        {"aa-exec-click -p pkg -- /usr/bin/webapp-container --store-session-cookies http://example.com:8080/app", "", true, "http://example.com:8080/app"},
+        {"webbrowser-app --webapp=R21haWw= https://mail.google.com/", "", true, "https://mail.google.com/"},
+        //The site is found on a best effort basis, but the app is a webapp even without one
+        {"webapp-container --homepage=https://news.example.com/ %u", "", true, "https://news.example.com/"},
+        {"webapp-container --webappUrlPatterns=https?://*.example.com/*,http://other.com/*", "", true, "https://example.com/"},
+        {"webapp-container --webappUrlPatterns=https?://*/*", "", true, ""},
+        {"webapp-container --webappModelSearchPath=.", "", true, ""},
+        {"webbrowser-app --webapp", "", true, ""},
+        //Older webapps are single instances of the browser
+        {"webbrowser-app --chrome https://mail.google.com/", "true", true, "https://mail.google.com/"},
+        {"webbrowser-app https://mail.google.com/", "", false, ""},
+        {"webbrowser-app %u", "true", false, ""},
+        {"aa-exec-click -p com.ubuntu.calculator_calculator_2.0 -- qmlscene calculator.qml", "true", false, ""},
+        {"webapp-container --unterminated \"", "", false, ""},
+    }
+
+    for _, c := range cases {
+        isWebapp, site := webapp(map[string]string{"exec": c.exec, "x-ubuntu-single-instance": c.singleInstance})
+        if isWebapp != c.isWebapp || site != c.site {
+            t.Errorf("expected %v, %q for %q, got %v, %q", c.isWebapp, c.site, c.exec, isWebapp, site)
+        }
+    }
+
+    for site, domain := range map[string]string{
+        "https://www.twitter.com/": "twitter.com",
+        "http://example.com:8080/app": "example.com",
+        "": "",
+    } {
+        if d := webappDomain(site); d != domain {
+            t.Errorf("expected %q for %q, got %q", domain, site, d)
+        }
+    }
+}
+
+func TestWebappCategory(t *testing.T) {
+    dir, err := ioutil.TempDir("", "falcon-webapps")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+
+    if err := ioutil.WriteFile(filepath.Join(dir, "twitter.desktop"), []byte(twitterDesktop), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    for _, separate := range []bool{false, true} {
+        falcon := newTestFalcon(layoutAppsScopes)
+        falcon.appDirs = append(falcon.appDirs, dir)
+        falcon.store.Update(func(state *State) error {
+            state.Settings.Webapps = separate
+            return nil
+        })
+
+        _, replayed := replayFile(t, falcon, filepath.Join("testdata", "layout-apps-scopes.jsonl"))
+
+        type result struct {
+            Category string `json:"category"`
+            Attrs struct {
+                Uri string `json:"uri"`
+                Subtitle string `json:"subtitle"`
+            } `json:"attrs"`
+        }
+
+        var twitter result
+        for _, entry := range replayed {
+            var r result
+            if entry.Request != 1 || entry.Kind != "result" || json.Unmarshal(entry.Data, &r) != nil {
+                continue
+            }
+
+            if r.Attrs.Uri == "application:///twitter.desktop" {
+                twitter = r
+            }
+        }
+
+        if separate && twitter.Category != "web-apps" {
+            t.Errorf("expected the webapp in its own category, got %+v", twitter)
+        } else if !separate && (twitter.Category != "apps" || twitter.Attrs.Subtitle != "Web App") {
+            t.Errorf("expected the webapp to be badged in the apps category, got %+v", twitter)
+        }
+    }
+}